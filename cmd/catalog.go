@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ioworker0/timage/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var catalogPrefix string
+
+var catalogCmd = &cobra.Command{
+	Use:   "catalog [registry]",
+	Short: "List the repositories of a registry",
+	Long: `List the repositories of a registry, using the credentials saved by timage login.
+
+Not every registry allows listing repositories; Docker Hub, for example, doesn't.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		registryURL := args[0]
+
+		// Get auth from config
+		configDir, err := config.GetConfigDir()
+		if err != nil {
+			cmd.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		cfg, err := config.NewManager(configDir)
+		if err != nil {
+			cmd.Printf("Error: Failed to load config: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Create registry client
+		client, err := newRegistryClient(cmd, cfg, registryURL)
+		if err != nil {
+			cmd.Printf("Error: Failed to create registry client: %v\n", err)
+			os.Exit(1)
+		}
+
+		repositories, err := client.Catalog(catalogPrefix)
+		if err != nil {
+			cmd.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// One repository per line on stdout so the output can be piped
+		for _, repository := range repositories {
+			fmt.Fprintln(cmd.OutOrStdout(), repository)
+		}
+	},
+}
+
+func init() {
+	catalogCmd.Flags().StringVar(&catalogPrefix, "prefix", "", "Only list repositories starting with this prefix, e.g. project/")
+	rootCmd.AddCommand(catalogCmd)
+}
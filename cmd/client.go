@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ioworker0/timage/pkg/config"
+	"github.com/ioworker0/timage/pkg/proxy"
+	"github.com/ioworker0/timage/pkg/registry"
+	"github.com/spf13/cobra"
+)
+
+// newRegistryClient creates a registry client using the credentials, proxy and
+// retry settings from the config, with command line flags taking priority
+func newRegistryClient(cmd *cobra.Command, cfg *config.Manager, registryURL string) (*registry.Client, error) {
+	// Get proxy URL: flag > environment > config
+	proxyFlag, _ := cmd.Flags().GetString("proxy")
+	proxyURL := proxy.GetProxyURL(proxyFlag)
+	if proxyURL == "" {
+		proxyURL = cfg.GetRegistryProxy(registryURL)
+	}
+
+	// Get auth credentials
+	username, password, _ := cfg.GetAuth(registryURL)
+	auth := &registry.AuthConfig{
+		Username: username,
+		Password: password,
+	}
+
+	// Create registry client
+	client, err := registry.NewClient(registryURL, auth, proxyURL)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := retryPolicy(cmd, cfg)
+	if err != nil {
+		return nil, err
+	}
+	client.SetRetryPolicy(policy)
+
+	// Chunked uploads: flag (on commands that have it) > registry config
+	chunkSize := cfg.GetRegistryUploadChunkSize(registryURL)
+	if flag := cmd.Flags().Lookup("chunk-size"); flag != nil && flag.Changed {
+		chunkSize = flag.Value.String()
+	}
+	if chunkSize != "" {
+		size, err := parseSize(chunkSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid upload chunk size: %w", err)
+		}
+		client.SetUploadChunkSize(size)
+	}
+
+	return client, nil
+}
+
+// parseSize parses a size such as "512KB", "64MB", "1GB" or a plain number of bytes
+func parseSize(s string) (int64, error) {
+	units := []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"GB", 1024 * 1024 * 1024},
+		{"MB", 1024 * 1024},
+		{"KB", 1024},
+		{"B", 1},
+	}
+
+	value := strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	return n * multiplier, nil
+}
+
+// retryPolicy builds the retry policy: flags > config > defaults
+func retryPolicy(cmd *cobra.Command, cfg *config.Manager) (registry.RetryPolicy, error) {
+	policy := registry.DefaultRetryPolicy()
+	retryCfg := cfg.GetRetryConfig()
+
+	if retryCfg.MaxAttempts > 0 {
+		policy.MaxAttempts = retryCfg.MaxAttempts
+	}
+	if retryCfg.InitialBackoff != "" {
+		d, err := time.ParseDuration(retryCfg.InitialBackoff)
+		if err != nil {
+			return policy, fmt.Errorf("invalid retry.initial_backoff in config: %w", err)
+		}
+		policy.InitialBackoff = d
+	}
+	if retryCfg.MaxBackoff != "" {
+		d, err := time.ParseDuration(retryCfg.MaxBackoff)
+		if err != nil {
+			return policy, fmt.Errorf("invalid retry.max_backoff in config: %w", err)
+		}
+		policy.MaxBackoff = d
+	}
+
+	if attempts, _ := cmd.Flags().GetInt("retry-attempts"); attempts > 0 {
+		policy.MaxAttempts = attempts
+	}
+	if backoff, _ := cmd.Flags().GetDuration("retry-backoff"); backoff > 0 {
+		policy.InitialBackoff = backoff
+	}
+
+	return policy, nil
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "0", want: 0},
+		{in: "1048576", want: 1048576},
+		{in: "100B", want: 100},
+		{in: "512KB", want: 512 * 1024},
+		{in: "512kb", want: 512 * 1024},
+		{in: "64MB", want: 64 * 1024 * 1024},
+		{in: " 64 MB ", want: 64 * 1024 * 1024},
+		{in: "1GB", want: 1024 * 1024 * 1024},
+		{in: "", wantErr: true},
+		{in: "MB", wantErr: true},
+		{in: "-1MB", wantErr: true},
+		{in: "1.5MB", wantErr: true},
+		{in: "64MiB", wantErr: true},
+		{in: "ten", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSize(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseSize(%q) = %d, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseSize(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
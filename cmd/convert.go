@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/ioworker0/timage/pkg/config"
+	"github.com/ioworker0/timage/pkg/registry"
+	"github.com/ioworker0/timage/pkg/storage"
+	"github.com/spf13/cobra"
+)
+
+var convertFormat string
+
+var convertCmd = &cobra.Command{
+	Use:   "convert --to oci|docker [source] [target]",
+	Short: "Convert a local image between the Docker and OCI formats",
+	Long: `Convert a local image between the Docker schema 2 and OCI image formats.
+
+The manifest, config and layer media types are rewritten and the result is stored
+as a new local image with a new manifest digest. Layers keep their content, except
+zstd-compressed layers, which are recompressed with gzip when converting to Docker.
+Manifest lists and OCI image indexes are converted together with every platform.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		source := args[0]
+		target := args[1]
+
+		format, err := registry.ParseFormat(convertFormat)
+		if err != nil {
+			cmd.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Get storage directory
+		storageDir, err := config.GetStorageDir()
+		if err != nil {
+			cmd.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Create store
+		store, err := storage.NewStore(storageDir)
+		if err != nil {
+			cmd.Printf("Error: Failed to create store: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Keep gc from collecting blobs before the manifest refers to them
+		lock, err := store.LockForWrite()
+		if err != nil {
+			cmd.Printf("Error: Failed to lock store: %v\n", err)
+			os.Exit(1)
+		}
+		defer lock.Unlock()
+
+		// Check if source exists
+		if !store.ImageExists(source) {
+			cmd.Printf("Error: Source image '%s' not found\n", source)
+			os.Exit(1)
+		}
+
+		digest, err := store.ConvertImage(source, target, format)
+		if err != nil {
+			cmd.Printf("Error: Failed to convert image: %v\n", err)
+			os.Exit(1)
+		}
+
+		cmd.Printf("Converted %s to %s as %s\n", source, format, target)
+		cmd.Printf("Digest: %s\n", digest)
+	},
+}
+
+func init() {
+	convertCmd.Flags().StringVar(&convertFormat, "to", "", "Target format: oci or docker")
+	convertCmd.MarkFlagRequired("to")
+	rootCmd.AddCommand(convertCmd)
+}
@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ioworker0/timage/pkg/config"
+	"github.com/ioworker0/timage/pkg/registry"
+	"github.com/spf13/cobra"
+)
+
+var copyCmd = &cobra.Command{
+	Use:   "copy [source] [destination]",
+	Short: "Copy an image from one registry to another",
+	Long: `Copy an image directly from one registry to another without storing it locally.
+
+Manifests and blobs are streamed between the registries, each using its own
+credentials and proxy settings. Blobs the destination already has are skipped,
+and within the same registry blobs are mounted instead of copied. Manifest lists
+and OCI image indexes are copied with the images for every platform, keeping
+their digests.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		srcRef := args[0]
+		dstRef := args[1]
+
+		// Parse image references
+		srcName, srcTag, srcRegistry := parseImageRef(srcRef)
+		dstName, dstTag, dstRegistry := parseImageRef(dstRef)
+
+		cmd.Printf("Copying %s to %s...\n", srcRef, dstRef)
+
+		// Get auth and proxy settings from config
+		configDir, err := config.GetConfigDir()
+		if err != nil {
+			cmd.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		cfg, err := config.NewManager(configDir)
+		if err != nil {
+			cmd.Printf("Error: Failed to load config: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Create a client for each registry
+		src, err := newRegistryClient(cmd, cfg, srcRegistry)
+		if err != nil {
+			cmd.Printf("Error: Failed to create registry client for %s: %v\n", srcRegistry, err)
+			os.Exit(1)
+		}
+
+		dst, err := newRegistryClient(cmd, cfg, dstRegistry)
+		if err != nil {
+			cmd.Printf("Error: Failed to create registry client for %s: %v\n", dstRegistry, err)
+			os.Exit(1)
+		}
+
+		c := &imageCopier{
+			cmd:          cmd,
+			src:          src,
+			srcName:      srcName,
+			dst:          dst,
+			dstName:      dstName,
+			sameRegistry: srcRegistry == dstRegistry,
+			verbose:      true,
+		}
+
+		digest, copied, err := c.copyTag(srcTag, dstTag, nil)
+		if err != nil {
+			cmd.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if !copied {
+			cmd.Printf("%s is up to date\n", dstRef)
+			cmd.Printf("Digest: %s\n", digest)
+			return
+		}
+
+		cmd.Printf("\nSuccessfully copied %s to %s\n", srcRef, dstRef)
+		cmd.Printf("Digest: %s\n", digest)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(copyCmd)
+}
+
+// imageCopier copies the content of an image between two repositories
+type imageCopier struct {
+	cmd          *cobra.Command
+	src          *registry.Client
+	srcName      string
+	dst          *registry.Client
+	dstName      string
+	sameRegistry bool
+	// verbose reports the progress of every manifest and blob
+	verbose bool
+}
+
+// logf prints progress in verbose mode
+func (c *imageCopier) logf(format string, args ...interface{}) {
+	if c.verbose {
+		c.cmd.Printf(format, args...)
+	}
+}
+
+// copyTag copies the manifest srcRef refers to, with everything it references, to dstTag
+// The manifest bytes are copied unchanged so the digest stays the same, unless platforms
+// is set: then a manifest list is reduced to the images for those platforms. Nothing is
+// copied if the destination already has the manifest. It returns the manifest digest
+// and whether anything was copied.
+func (c *imageCopier) copyTag(srcRef, dstTag string, platforms []registry.Platform) (string, bool, error) {
+	manifestRaw, mediaType, err := c.sourceManifest(srcRef, platforms)
+	if err != nil {
+		return "", false, err
+	}
+
+	// Skip manifests the destination already has
+	digest := registry.ComputeDigest(manifestRaw)
+	if c.hasManifest(dstTag, digest) {
+		return digest, false, nil
+	}
+
+	isIndex := registry.IsManifestList(mediaType)
+
+	// Within one repository everything the manifest refers to is already there
+	if !c.sameRegistry || c.srcName != c.dstName {
+		if isIndex {
+			err = c.copyIndex(manifestRaw)
+		} else {
+			err = c.copyManifestBlobs(manifestRaw)
+		}
+		if err != nil {
+			return "", false, err
+		}
+	}
+
+	c.logf("Uploading manifest...\n")
+	if err := c.dst.PutManifest(c.dstName, dstTag, manifestRaw, mediaType); err != nil {
+		return "", false, fmt.Errorf("failed to upload manifest: %w", err)
+	}
+
+	return digest, true, nil
+}
+
+// checkTag reports whether the destination already has the manifest copyTag would copy
+func (c *imageCopier) checkTag(srcRef, dstTag string, platforms []registry.Platform) (string, bool, error) {
+	manifestRaw, _, err := c.sourceManifest(srcRef, platforms)
+	if err != nil {
+		return "", false, err
+	}
+
+	digest := registry.ComputeDigest(manifestRaw)
+	return digest, c.hasManifest(dstTag, digest), nil
+}
+
+// sourceManifest fetches a source manifest and its media type, reducing a manifest
+// list to the given platforms; platforms the list lacks are skipped with a warning
+func (c *imageCopier) sourceManifest(srcRef string, platforms []registry.Platform) ([]byte, string, error) {
+	manifestRaw, contentType, err := c.src.GetManifestRaw(c.srcName, srcRef)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get manifest: %w", err)
+	}
+	mediaType := registry.DetectMediaType(manifestRaw, contentType)
+
+	if registry.IsManifestList(mediaType) && len(platforms) > 0 {
+		var missing []registry.Platform
+		manifestRaw, missing, err = registry.FilterIndex(manifestRaw, platforms)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, platform := range missing {
+			c.cmd.Printf("  %s: Warning: no image for %s, skipping it\n", srcRef, platform)
+		}
+	}
+
+	return manifestRaw, mediaType, nil
+}
+
+// hasManifest reports whether a destination tag points at the manifest with digest
+func (c *imageCopier) hasManifest(dstTag, digest string) bool {
+	existing, err := c.dst.GetManifestDigest(c.dstName, dstTag)
+	return err == nil && existing == digest
+}
+
+// copyIndex copies every child image of a manifest list or index by digest
+func (c *imageCopier) copyIndex(indexData []byte) error {
+	var index registry.Manifest
+	if err := json.Unmarshal(indexData, &index); err != nil {
+		return fmt.Errorf("failed to parse manifest list: %w", err)
+	}
+
+	for i, entry := range index.Manifests {
+		c.logf("Copying %s [%d/%d]...\n", entry.Platform, i+1, len(index.Manifests))
+
+		childData, contentType, err := c.src.GetManifestRaw(c.srcName, entry.Digest)
+		if err != nil {
+			return fmt.Errorf("failed to get manifest for %s: %w", entry.Platform, err)
+		}
+		mediaType := registry.DetectMediaType(childData, contentType)
+		if registry.IsManifestList(mediaType) {
+			return fmt.Errorf("nested manifest lists are not supported")
+		}
+
+		if err := c.copyManifestBlobs(childData); err != nil {
+			return err
+		}
+
+		// Push by digest; the exact bytes are needed for the index to stay valid
+		if err := c.dst.PutManifest(c.dstName, entry.Digest, childData, mediaType); err != nil {
+			return fmt.Errorf("failed to upload manifest for %s: %w", entry.Platform, err)
+		}
+	}
+
+	return nil
+}
+
+// copyManifestBlobs copies the config and layers of a manifest
+func (c *imageCopier) copyManifestBlobs(manifestData []byte) error {
+	var manifest registry.Manifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	c.logf("Copying config...\n")
+	if err := c.copyBlob(manifest.Config); err != nil {
+		return fmt.Errorf("failed to copy config: %w", err)
+	}
+
+	c.logf("Copying %d layers...\n", len(manifest.Layers))
+	for i, layer := range manifest.Layers {
+		c.logf("  [%d/%d] %s\n", i+1, len(manifest.Layers), shortDigest(layer.Digest))
+		if err := c.copyBlob(layer); err != nil {
+			return fmt.Errorf("failed to copy layer: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// copyBlob makes a blob available in the destination repository
+// It skips blobs the destination already has, mounts blobs within the same
+// registry, and otherwise streams the blob from the source.
+func (c *imageCopier) copyBlob(blob registry.Layer) error {
+	exists, err := c.dst.CheckBlob(c.dstName, blob.Digest)
+	if err == nil && exists {
+		c.logf("    Already exists, skipping\n")
+		return nil
+	}
+
+	if c.sameRegistry && c.srcName != c.dstName {
+		mounted, err := c.dst.MountBlob(c.dstName, blob.Digest, c.srcName)
+		if err == nil && mounted {
+			c.logf("    Mounted from %s\n", c.srcName)
+			return nil
+		}
+	}
+
+	if err := registry.CopyBlob(c.src, c.srcName, c.dst, c.dstName, blob.Digest, blob.Size, nil); err != nil {
+		return err
+	}
+	c.logf("    Copied %s\n", formatBytes(blob.Size))
+
+	return nil
+}
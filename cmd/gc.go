@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/ioworker0/timage/pkg/config"
+	"github.com/ioworker0/timage/pkg/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	gcDryRun bool
+	gcMinAge time.Duration
+)
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove unreferenced blobs and leftover data from interrupted pulls and loads",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Get storage directory
+		storageDir, err := config.GetStorageDir()
+		if err != nil {
+			cmd.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Create store
+		store, err := storage.NewStore(storageDir)
+		if err != nil {
+			cmd.Printf("Error: Failed to create store: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Collect garbage
+		result, err := store.GarbageCollect(storage.GCOptions{
+			DryRun: gcDryRun,
+			MinAge: gcMinAge,
+		})
+		if err != nil {
+			cmd.Printf("Error: Garbage collection failed: %v\n", err)
+			os.Exit(1)
+		}
+
+		action := "Removed"
+		if gcDryRun {
+			action = "Would remove"
+		}
+
+		for _, dir := range result.RemovedImageDirs {
+			cmd.Printf("%s incomplete image: %s\n", action, dir)
+		}
+		for _, digest := range result.RemovedBlobs {
+			cmd.Printf("%s blob: %s\n", action, digest)
+		}
+		for _, path := range result.RemovedTempFiles {
+			cmd.Printf("%s temp file: %s\n", action, path)
+		}
+		for _, dir := range result.RemovedTempDirs {
+			cmd.Printf("%s temp directory: %s\n", action, dir)
+		}
+
+		total := len(result.RemovedImageDirs) + len(result.RemovedBlobs) + len(result.RemovedTempFiles) + len(result.RemovedTempDirs)
+		if total == 0 {
+			cmd.Println("Nothing to clean up")
+			return
+		}
+
+		if gcDryRun {
+			cmd.Printf("\nTotal reclaimable space: %s\n", formatBytes(result.FreedBytes))
+		} else {
+			cmd.Printf("\nTotal reclaimed space: %s\n", formatBytes(result.FreedBytes))
+		}
+	},
+}
+
+func init() {
+	gcCmd.Flags().BoolVar(&gcDryRun, "dry-run", false, "Show what would be removed without removing anything")
+	gcCmd.Flags().DurationVar(&gcMinAge, "min-age", 10*time.Minute, "Only remove data older than this, to protect data written by other tools")
+	rootCmd.AddCommand(gcCmd)
+}
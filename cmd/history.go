@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ioworker0/timage/pkg/registry"
+	"github.com/ioworker0/timage/pkg/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyRemote   bool
+	historyPlatform string
+	historyNoTrunc  bool
+	historyNoSizes  bool
+)
+
+// historyCreatedByWidth is how much of a step's command is shown without --no-trunc
+const historyCreatedByWidth = 60
+
+var historyCmd = &cobra.Command{
+	Use:   "history [image]",
+	Short: "Show the build steps of an image and the size of each layer",
+	Long: `Show the steps an image was built from, oldest first, with the compressed and
+uncompressed size of the layer each step created, e.g.
+
+  timage history nginx:latest
+  timage history --remote nginx:latest --platform linux/arm64
+
+Uncompressed sizes are measured by decompressing each layer. With --remote,
+layers that aren't in local storage are streamed from the registry for this;
+use --skip-uncompressed to only read the manifest and config.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		imageRef := args[0]
+
+		// Determine the platform to show for manifest lists
+		platform := registry.DefaultPlatform()
+		if historyPlatform != "" {
+			var err error
+			platform, err = registry.ParsePlatform(historyPlatform)
+			if err != nil {
+				cmd.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		image, err := loadImage(cmd, imageRef, historyRemote, platform)
+		if err != nil {
+			cmd.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		layers := image.Manifest.Layers
+		if err := image.Config.ValidateHistory(layers); err != nil {
+			cmd.Printf("Warning: %v\n", err)
+		}
+
+		steps := joinHistory(image.Config.History, layers)
+
+		// Measure every layer once; the same layer can appear more than once
+		uncompressed := make(map[string]int64)
+		for _, layer := range layers {
+			if historyNoSizes {
+				uncompressed[layer.Digest] = -1
+				continue
+			}
+			if _, ok := uncompressed[layer.Digest]; ok {
+				continue
+			}
+			size, err := uncompressedLayerSize(image, layer)
+			if err != nil {
+				cmd.Printf("Warning: Failed to measure layer %s: %v\n", shortDigest(layer.Digest), err)
+				size = -1
+			}
+			uncompressed[layer.Digest] = size
+		}
+
+		out := cmd.OutOrStdout()
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CREATED\tLAYER\tSIZE\tUNCOMPRESSED\tCREATED BY")
+
+		var total, totalUncompressed int64
+		for _, step := range steps {
+			created := "-"
+			if step.created != nil {
+				created = step.created.Format(time.RFC3339)
+			}
+
+			layerID, size, uncompressedSize := "-", "-", "-"
+			switch {
+			case step.layer != nil:
+				layerID = shortDigest(step.layer.Digest)
+				size = formatBytes(step.layer.Size)
+				total += step.layer.Size
+				if n := uncompressed[step.layer.Digest]; n >= 0 {
+					uncompressedSize = formatBytes(n)
+					totalUncompressed += n
+				}
+			case step.emptyLayer:
+				size, uncompressedSize = "0 B", "0 B"
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", created, layerID, size, uncompressedSize, formatCreatedBy(step.createdBy, historyNoTrunc))
+		}
+		w.Flush()
+
+		if historyNoSizes {
+			fmt.Fprintf(out, "\nTotal: %s compressed\n", formatBytes(total))
+			return
+		}
+		fmt.Fprintf(out, "\nTotal: %s compressed, %s uncompressed\n", formatBytes(total), formatBytes(totalUncompressed))
+	},
+}
+
+// historyStep is a history entry joined with the layer it created
+type historyStep struct {
+	created    *time.Time
+	createdBy  string
+	emptyLayer bool
+	layer      *registry.Descriptor
+}
+
+// joinHistory pairs history entries with layers
+// Every entry not marked empty_layer created the next layer of the manifest.
+// History is optional and sometimes incomplete: layers without an entry are
+// listed at the end, and entries left without a layer have none.
+func joinHistory(history []registry.History, layers []registry.Descriptor) []historyStep {
+	steps := make([]historyStep, 0, len(history))
+
+	next := 0
+	for _, entry := range history {
+		step := historyStep{
+			created:    entry.Created,
+			createdBy:  entry.CreatedBy,
+			emptyLayer: entry.EmptyLayer,
+		}
+		if !entry.EmptyLayer && next < len(layers) {
+			step.layer = &layers[next]
+			next++
+		}
+		steps = append(steps, step)
+	}
+
+	for ; next < len(layers); next++ {
+		steps = append(steps, historyStep{layer: &layers[next]})
+	}
+
+	return steps
+}
+
+// uncompressedLayerSize decompresses a layer to measure the size of its tar
+// The layer is verified against its digest, since it may come from the registry.
+func uncompressedLayerSize(image *imageData, layer registry.Descriptor) (int64, error) {
+	info, err := registry.GetLayerInfo(layer.MediaType)
+	if err != nil {
+		return 0, err
+	}
+
+	blob, err := image.OpenBlob(layer.Digest)
+	if err != nil {
+		return 0, err
+	}
+	defer blob.Close()
+
+	verified, err := registry.NewVerifyingReader(layer.Digest, blob)
+	if err != nil {
+		return 0, err
+	}
+
+	reader, err := storage.DecompressLayer(verified, info.Compression)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	size, err := io.Copy(io.Discard, reader)
+	if err != nil {
+		return 0, err
+	}
+
+	// Read whatever the decompressor left so the whole blob is verified
+	if _, err := io.Copy(io.Discard, verified); err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// formatCreatedBy puts a step's command on one line, shortened unless noTrunc is set
+func formatCreatedBy(createdBy string, noTrunc bool) string {
+	createdBy = strings.Join(strings.Fields(createdBy), " ")
+	if runes := []rune(createdBy); !noTrunc && len(runes) > historyCreatedByWidth {
+		createdBy = string(runes[:historyCreatedByWidth-3]) + "..."
+	}
+	return createdBy
+}
+
+func init() {
+	historyCmd.Flags().BoolVar(&historyRemote, "remote", false, "Show the history of the image in its registry instead of local storage")
+	historyCmd.Flags().StringVar(&historyPlatform, "platform", "",
+		fmt.Sprintf("Platform to show for multi-arch images, as os/arch[/variant] (default %s)", registry.DefaultPlatform()))
+	historyCmd.Flags().BoolVar(&historyNoTrunc, "no-trunc", false, "Don't shorten the commands of build steps")
+	historyCmd.Flags().BoolVar(&historyNoSizes, "skip-uncompressed", false, "Don't measure uncompressed layer sizes, which reads every layer")
+	rootCmd.AddCommand(historyCmd)
+}
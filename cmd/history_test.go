@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ioworker0/timage/pkg/registry"
+)
+
+func TestJoinHistory(t *testing.T) {
+	layers := []registry.Descriptor{{Digest: "sha256:a"}, {Digest: "sha256:b"}}
+
+	tests := []struct {
+		name    string
+		history []registry.History
+		// Each step as createdBy=layer digest, or createdBy=- without a layer
+		want []string
+	}{
+		{
+			name: "one entry per layer",
+			history: []registry.History{
+				{CreatedBy: "ADD rootfs"},
+				{CreatedBy: "RUN make"},
+			},
+			want: []string{"ADD rootfs=sha256:a", "RUN make=sha256:b"},
+		},
+		{
+			name: "empty layers are skipped",
+			history: []registry.History{
+				{CreatedBy: "ADD rootfs"},
+				{CreatedBy: "ENV A=1", EmptyLayer: true},
+				{CreatedBy: "RUN make"},
+				{CreatedBy: "CMD sh", EmptyLayer: true},
+			},
+			want: []string{"ADD rootfs=sha256:a", "ENV A=1=-", "RUN make=sha256:b", "CMD sh=-"},
+		},
+		{
+			name:    "no history",
+			history: nil,
+			want:    []string{"=sha256:a", "=sha256:b"},
+		},
+		{
+			name:    "layers without an entry are listed at the end",
+			history: []registry.History{{CreatedBy: "ADD rootfs"}},
+			want:    []string{"ADD rootfs=sha256:a", "=sha256:b"},
+		},
+		{
+			name: "entries without a layer",
+			history: []registry.History{
+				{CreatedBy: "ADD rootfs"},
+				{CreatedBy: "RUN make"},
+				{CreatedBy: "RUN test"},
+			},
+			want: []string{"ADD rootfs=sha256:a", "RUN make=sha256:b", "RUN test=-"},
+		},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, step := range joinHistory(tt.history, layers) {
+			layer := "-"
+			if step.layer != nil {
+				layer = step.layer.Digest
+			}
+			got = append(got, step.createdBy+"="+layer)
+		}
+		if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+			t.Errorf("%s: joinHistory = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFormatCreatedBy(t *testing.T) {
+	long := strings.Repeat("x", historyCreatedByWidth+10)
+	wide := strings.Repeat("é", historyCreatedByWidth+10)
+
+	tests := []struct {
+		in      string
+		noTrunc bool
+		want    string
+	}{
+		{in: "/bin/sh -c #(nop)  CMD [\"sh\"]", want: "/bin/sh -c #(nop) CMD [\"sh\"]"},
+		{in: "RUN apk add \\\n\tcurl", want: "RUN apk add \\ curl"},
+		{in: long, want: long[:historyCreatedByWidth-3] + "..."},
+		{in: long, noTrunc: true, want: long},
+		// Truncated by characters, never in the middle of one
+		{in: wide, want: strings.Repeat("é", historyCreatedByWidth-3) + "..."},
+		{in: strings.Repeat("é", historyCreatedByWidth), want: strings.Repeat("é", historyCreatedByWidth)},
+	}
+
+	for _, tt := range tests {
+		if got := formatCreatedBy(tt.in, tt.noTrunc); got != tt.want {
+			t.Errorf("formatCreatedBy(%q, %v) = %q, want %q", tt.in, tt.noTrunc, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/ioworker0/timage/pkg/config"
+	"github.com/ioworker0/timage/pkg/registry"
+	"github.com/ioworker0/timage/pkg/storage"
+	"github.com/spf13/cobra"
+)
+
+// imageData is the manifest and config of an image, from local storage or a registry
+type imageData struct {
+	// IndexDigest and IndexMediaType are set when the reference points to a
+	// manifest list or index; the rest describes the image for the selected platform
+	IndexDigest    string
+	IndexMediaType string
+
+	Digest    string
+	MediaType string
+	Manifest  *registry.OCIManifest
+	Config    *registry.ImageConfig
+
+	// OpenBlob reads a layer of the image, from local storage when it's there
+	OpenBlob func(digest string) (io.ReadCloser, error)
+}
+
+// loadImage reads the manifest and config of an image for a platform
+// With remote set the image is read from its registry, otherwise from local storage.
+func loadImage(cmd *cobra.Command, imageRef string, remote bool, platform registry.Platform) (*imageData, error) {
+	storageDir, err := config.GetStorageDir()
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := storage.NewStore(storageDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create store: %w", err)
+	}
+
+	image := &imageData{}
+
+	var fetchManifest func(reference string) ([]byte, string, error)
+	var fetchBlob func(digest string) ([]byte, error)
+
+	if remote {
+		name, reference, registryURL := parseImageRef(imageRef)
+
+		// Get auth from config
+		configDir, err := config.GetConfigDir()
+		if err != nil {
+			return nil, err
+		}
+
+		cfg, err := config.NewManager(configDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config: %w", err)
+		}
+
+		client, err := newRegistryClient(cmd, cfg, registryURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create registry client: %w", err)
+		}
+
+		fetchManifest = func(digest string) ([]byte, string, error) {
+			if digest == "" {
+				digest = reference
+			}
+			return client.GetManifestRaw(name, digest)
+		}
+		fetchBlob = func(digest string) ([]byte, error) {
+			return client.GetBlobContent(name, digest)
+		}
+		// Blobs are content addressed, so a local copy is as good as the registry's
+		image.OpenBlob = func(digest string) (io.ReadCloser, error) {
+			if store.HasBlob(digest) {
+				return store.OpenBlob(digest)
+			}
+			body, _, err := client.GetBlob(name, digest)
+			return body, err
+		}
+	} else {
+		if !store.ImageExists(imageRef) {
+			return nil, fmt.Errorf("image '%s' not found locally", imageRef)
+		}
+
+		fetchManifest = func(digest string) ([]byte, string, error) {
+			if digest == "" {
+				data, err := store.LoadManifestRaw(imageRef)
+				return data, "", err
+			}
+			data, err := store.LoadChildManifestRaw(digest)
+			return data, "", err
+		}
+		fetchBlob = store.LoadBlob
+		image.OpenBlob = store.OpenBlob
+	}
+
+	data, contentType, err := fetchManifest("")
+	if err != nil {
+		return nil, err
+	}
+	image.MediaType = registry.DetectMediaType(data, contentType)
+	image.Digest = registry.ComputeDigest(data)
+
+	// Resolve a manifest list to the image for the platform
+	if registry.IsManifestList(image.MediaType) {
+		var index registry.Manifest
+		if err := json.Unmarshal(data, &index); err != nil {
+			return nil, fmt.Errorf("failed to parse manifest list: %w", err)
+		}
+
+		entry, err := registry.SelectManifest(index.Manifests, platform)
+		if err != nil {
+			return nil, err
+		}
+
+		image.IndexDigest = image.Digest
+		image.IndexMediaType = image.MediaType
+
+		data, contentType, err = fetchManifest(entry.Digest)
+		if err != nil {
+			return nil, err
+		}
+		image.MediaType = registry.DetectMediaType(data, contentType)
+		image.Digest = entry.Digest
+	}
+
+	image.Manifest, err = registry.ParseOCIManifest(data)
+	if err != nil {
+		return nil, err
+	}
+
+	configData, err := fetchBlob(image.Manifest.Config.Digest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	image.Config, err = registry.ParseImageConfig(configData)
+	if err != nil {
+		return nil, err
+	}
+
+	return image, nil
+}
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/ioworker0/timage/pkg/registry"
+	"github.com/spf13/cobra"
+)
+
+var (
+	inspectRemote   bool
+	inspectPlatform string
+	inspectFormat   string
+)
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect [image]",
+	Short: "Show the manifest and configuration of an image",
+	Long: `Show the digest, layers and configuration of a local image, or of an image
+in a registry with --remote, e.g.
+
+  timage inspect nginx:latest
+  timage inspect --remote nginx:latest --platform linux/arm64
+  timage inspect nginx:latest --format '{{.Config.Config.Cmd}}'`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		imageRef := args[0]
+
+		// Determine the platform to show for manifest lists
+		platform := registry.DefaultPlatform()
+		if inspectPlatform != "" {
+			var err error
+			platform, err = registry.ParsePlatform(inspectPlatform)
+			if err != nil {
+				cmd.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		// Parse the template before doing any work
+		var tmpl *template.Template
+		if inspectFormat != "" && inspectFormat != "json" {
+			var err error
+			tmpl, err = template.New("format").Funcs(templateFuncs).Parse(inspectFormat)
+			if err != nil {
+				cmd.Printf("Error: Invalid format: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		image, err := loadImage(cmd, imageRef, inspectRemote, platform)
+		if err != nil {
+			cmd.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		details := newImageDetails(imageRef, image)
+
+		out := cmd.OutOrStdout()
+		switch {
+		case tmpl != nil:
+			if err := tmpl.Execute(out, details); err != nil {
+				cmd.Printf("Error: Failed to format output: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Fprintln(out)
+		case inspectFormat == "json":
+			data, err := json.MarshalIndent(details, "", "  ")
+			if err != nil {
+				cmd.Printf("Error: Failed to format output: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Fprintln(out, string(data))
+		default:
+			printImageDetails(out, details)
+		}
+	},
+}
+
+// imageDetails is the output of inspect, and the data --format templates see
+type imageDetails struct {
+	Name           string
+	Digest         string
+	MediaType      string
+	IndexDigest    string `json:",omitempty"`
+	IndexMediaType string `json:",omitempty"`
+	ConfigDigest   string
+	Size           int64
+	Layers         []registry.Descriptor
+	Config         *registry.ImageConfig
+}
+
+// newImageDetails collects what inspect shows about an image
+func newImageDetails(name string, image *imageData) *imageDetails {
+	details := &imageDetails{
+		Name:           name,
+		Digest:         image.Digest,
+		MediaType:      image.MediaType,
+		IndexDigest:    image.IndexDigest,
+		IndexMediaType: image.IndexMediaType,
+		ConfigDigest:   image.Manifest.Config.Digest,
+		Layers:         image.Manifest.Layers,
+		Config:         image.Config,
+	}
+	for _, layer := range image.Manifest.Layers {
+		details.Size += layer.Size
+	}
+	return details
+}
+
+// templateFuncs are the functions available to --format templates
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// printImageDetails writes the human-readable form of inspect
+func printImageDetails(out io.Writer, details *imageDetails) {
+	config := details.Config
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", details.Name)
+	fmt.Fprintf(w, "Digest:\t%s\n", details.Digest)
+	fmt.Fprintf(w, "Media type:\t%s\n", details.MediaType)
+	if details.IndexDigest != "" {
+		fmt.Fprintf(w, "Index digest:\t%s\n", details.IndexDigest)
+	}
+	fmt.Fprintf(w, "Platform:\t%s\n", config.Platform())
+	if config.Created != nil {
+		fmt.Fprintf(w, "Created:\t%s\n", config.Created.Format(time.RFC3339))
+	}
+	fmt.Fprintf(w, "Size:\t%s (compressed)\n", formatBytes(details.Size))
+	fmt.Fprintf(w, "Config digest:\t%s\n", details.ConfigDigest)
+	w.Flush()
+
+	fmt.Fprintf(out, "\nConfig:\n")
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  User:\t%s\n", config.Config.User)
+	fmt.Fprintf(w, "  WorkingDir:\t%s\n", config.Config.WorkingDir)
+	fmt.Fprintf(w, "  Entrypoint:\t%s\n", formatCommand(config.Config.Entrypoint))
+	fmt.Fprintf(w, "  Cmd:\t%s\n", formatCommand(config.Config.Cmd))
+	fmt.Fprintf(w, "  ExposedPorts:\t%s\n", strings.Join(sortedKeys(config.Config.ExposedPorts), ", "))
+	w.Flush()
+
+	if len(config.Config.Env) > 0 {
+		fmt.Fprintf(out, "  Env:\n")
+		for _, env := range config.Config.Env {
+			fmt.Fprintf(out, "    %s\n", env)
+		}
+	}
+	if len(config.Config.Labels) > 0 {
+		fmt.Fprintf(out, "  Labels:\n")
+		for _, key := range sortedKeys(config.Config.Labels) {
+			fmt.Fprintf(out, "    %s=%s\n", key, config.Config.Labels[key])
+		}
+	}
+
+	fmt.Fprintf(out, "\nLayers (%d):\n", len(details.Layers))
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, layer := range details.Layers {
+		fmt.Fprintf(w, "  %s\t%s\n", layer.Digest, formatBytes(layer.Size))
+	}
+	w.Flush()
+
+	if len(config.History) > 0 {
+		fmt.Fprintf(out, "\nHistory (%d):\n", len(config.History))
+		w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		for _, entry := range config.History {
+			created := "-"
+			if entry.Created != nil {
+				created = entry.Created.Format(time.RFC3339)
+			}
+			createdBy := entry.CreatedBy
+			if entry.EmptyLayer {
+				createdBy += " (empty layer)"
+			}
+			fmt.Fprintf(w, "  %s\t%s\n", created, createdBy)
+		}
+		w.Flush()
+	}
+}
+
+// formatCommand formats an Entrypoint or Cmd the way a Dockerfile writes it
+func formatCommand(args []string) string {
+	if args == nil {
+		return ""
+	}
+	data, _ := json.Marshal(args)
+	return string(data)
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func init() {
+	inspectCmd.Flags().BoolVar(&inspectRemote, "remote", false, "Inspect the image in its registry instead of local storage")
+	inspectCmd.Flags().StringVar(&inspectPlatform, "platform", "",
+		fmt.Sprintf("Platform to show for multi-arch images, as os/arch[/variant] (default %s)", registry.DefaultPlatform()))
+	inspectCmd.Flags().StringVar(&inspectFormat, "format", "", "Output format: json, or a Go template")
+	rootCmd.AddCommand(inspectCmd)
+}
@@ -1,18 +1,72 @@
 package cmd
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
 
 	"github.com/ioworker0/timage/pkg/config"
 	"github.com/ioworker0/timage/pkg/storage"
 	"github.com/spf13/cobra"
 )
 
+// Orders list can sort images in
+const (
+	listSortName    = "name"
+	listSortCreated = "created"
+	listSortSize    = "size"
+)
+
+var (
+	listFilters []string
+	listSort    string
+	listQuiet   bool
+	listFormat  string
+)
+
 var listCmd = &cobra.Command{
-	Use:   "list",
-	Short: "List local images",
+	Use:     "list",
+	Short:   "List local images",
 	Aliases: []string{"ls"},
+	Long: `List local images with their digest, creation time and size on disk, e.g.
+
+  timage list
+  timage list --filter reference='harbor.example.com/*' --filter label=team=infra
+  timage list --filter since=nginx:1.25 --sort created
+  timage list --format '{{.Name}} {{.Digest}}'
+
+Filters:
+  reference=<pattern>    name or repository matches a glob pattern
+  label=<key>[=<value>]  config has the label, with the value if given
+  before=<image|time>    created before an image or RFC 3339 time
+  since=<image|time>     created after an image or RFC 3339 time
+  dangling[=true|false]  image has no tag (stored by digest)
+
+Several reference filters match any of them; different filters must all match.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		// Parse the output format before doing any work
+		var tmpl *template.Template
+		if listFormat != "" && listFormat != "table" && listFormat != "json" {
+			var err error
+			tmpl, err = template.New("format").Funcs(templateFuncs).Parse(listFormat)
+			if err != nil {
+				cmd.Printf("Error: Invalid format: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		switch listSort {
+		case listSortName, listSortCreated, listSortSize:
+		default:
+			cmd.Printf("Error: Unknown sort order %q (expected name, created or size)\n", listSort)
+			os.Exit(1)
+		}
+
 		// Get storage directory
 		storageDir, err := config.GetStorageDir()
 		if err != nil {
@@ -27,28 +81,224 @@ var listCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		filter, err := parseImageFilters(store, listFilters)
+		if err != nil {
+			cmd.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
 		// List images
-		images, err := store.ListImages()
+		infos, err := store.ListImageInfo()
 		if err != nil {
 			cmd.Printf("Error: Failed to list images: %v\n", err)
 			os.Exit(1)
 		}
 
-		// Display images
-		if len(images) == 0 {
-			cmd.Println("No images found")
-			return
+		images := make([]storage.ImageInfo, 0, len(infos))
+		for _, info := range infos {
+			if filter.match(&info) {
+				images = append(images, info)
+			}
 		}
+		sortImages(images, listSort)
 
-		cmd.Println("Local images:")
-		for _, image := range images {
-			cmd.Printf("  %s\n", image)
-		}
+		out := cmd.OutOrStdout()
+		switch {
+		case listQuiet:
+			for _, image := range images {
+				fmt.Fprintln(out, image.Name)
+			}
+		case tmpl != nil:
+			for _, image := range images {
+				if err := tmpl.Execute(out, image); err != nil {
+					cmd.Printf("Error: Failed to format output: %v\n", err)
+					os.Exit(1)
+				}
+				fmt.Fprintln(out)
+			}
+		case listFormat == "json":
+			data, err := json.MarshalIndent(images, "", "  ")
+			if err != nil {
+				cmd.Printf("Error: Failed to format output: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Fprintln(out, string(data))
+		default:
+			// Display images
+			if len(images) == 0 {
+				cmd.Println("No images found")
+				return
+			}
+
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "REPOSITORY\tTAG\tDIGEST\tCREATED\tSIZE")
+			for _, image := range images {
+				tag := image.Tag
+				if image.Dangling() {
+					tag = "<none>"
+				}
+				digest := "-"
+				if image.Digest != "" {
+					digest = shortDigest(image.Digest)
+				}
+				created := "-"
+				if image.Created != nil {
+					created = image.Created.Format(time.RFC3339)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", image.Repository, tag, digest, created, formatBytes(image.Size))
+			}
+			w.Flush()
 
-		cmd.Printf("\nTotal: %d image(s)\n", len(images))
+			fmt.Fprintf(out, "\nTotal: %d image(s)\n", len(images))
+		}
 	},
 }
 
+// imageFilter selects images for list
+type imageFilter struct {
+	references []string
+	labels     []string
+	before     *time.Time
+	since      *time.Time
+	dangling   *bool
+}
+
+// parseImageFilters parses --filter values
+func parseImageFilters(store *storage.Store, filters []string) (*imageFilter, error) {
+	filter := &imageFilter{}
+
+	for _, f := range filters {
+		key, value, hasValue := strings.Cut(f, "=")
+		switch key {
+		case "reference":
+			if _, err := path.Match(value, ""); err != nil {
+				return nil, fmt.Errorf("invalid reference filter %q: %w", value, err)
+			}
+			filter.references = append(filter.references, value)
+		case "label":
+			if value == "" {
+				return nil, fmt.Errorf("label filter needs a key")
+			}
+			filter.labels = append(filter.labels, value)
+		case "before", "since":
+			created, err := filterTime(store, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s filter: %w", key, err)
+			}
+			if key == "before" {
+				filter.before = created
+			} else {
+				filter.since = created
+			}
+		case "dangling":
+			dangling := true
+			switch value {
+			case "true", "1":
+			case "false", "0":
+				dangling = false
+			default:
+				if hasValue {
+					return nil, fmt.Errorf("invalid dangling filter %q (expected true or false)", value)
+				}
+			}
+			filter.dangling = &dangling
+		default:
+			return nil, fmt.Errorf("unknown filter %q (expected reference, label, before, since or dangling)", key)
+		}
+	}
+
+	return filter, nil
+}
+
+// filterTime resolves the value of a before or since filter to a time
+// The value names a local image, whose creation time is used, or is a time itself.
+func filterTime(store *storage.Store, value string) (*time.Time, error) {
+	if store.ImageExists(value) {
+		info, err := store.GetImageInfo(value)
+		if err != nil {
+			return nil, err
+		}
+		if info.Created == nil {
+			return nil, fmt.Errorf("image '%s' has no creation time", value)
+		}
+		return info.Created, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+
+	return nil, fmt.Errorf("'%s' is neither a local image nor a time", value)
+}
+
+// match reports whether an image passes every filter
+func (f *imageFilter) match(image *storage.ImageInfo) bool {
+	if len(f.references) > 0 {
+		matched := false
+		for _, pattern := range f.references {
+			if ok, _ := path.Match(pattern, image.Name); ok {
+				matched = true
+				break
+			}
+			if ok, _ := path.Match(pattern, image.Repository); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	for _, label := range f.labels {
+		key, value, hasValue := strings.Cut(label, "=")
+		actual, ok := image.Labels[key]
+		if !ok || (hasValue && actual != value) {
+			return false
+		}
+	}
+
+	if f.before != nil && (image.Created == nil || !image.Created.Before(*f.before)) {
+		return false
+	}
+	if f.since != nil && (image.Created == nil || !image.Created.After(*f.since)) {
+		return false
+	}
+
+	if f.dangling != nil && image.Dangling() != *f.dangling {
+		return false
+	}
+
+	return true
+}
+
+// sortImages sorts images by name, newest first, or largest first
+func sortImages(images []storage.ImageInfo, order string) {
+	sort.SliceStable(images, func(i, j int) bool {
+		a, b := images[i], images[j]
+		switch order {
+		case listSortCreated:
+			if a.Created == nil || b.Created == nil {
+				return a.Created != nil
+			}
+			if !a.Created.Equal(*b.Created) {
+				return a.Created.After(*b.Created)
+			}
+		case listSortSize:
+			if a.Size != b.Size {
+				return a.Size > b.Size
+			}
+		}
+		return a.Name < b.Name
+	})
+}
+
 func init() {
+	listCmd.Flags().StringArrayVarP(&listFilters, "filter", "f", nil, "Only show images matching a filter, e.g. reference=nginx* (can be repeated)")
+	listCmd.Flags().StringVar(&listSort, "sort", listSortName, "Sort by name, created (newest first) or size (largest first)")
+	listCmd.Flags().BoolVarP(&listQuiet, "quiet", "q", false, "Only show image names")
+	listCmd.Flags().StringVar(&listFormat, "format", "table", "Output format: table, json, or a Go template")
 	rootCmd.AddCommand(listCmd)
 }
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ioworker0/timage/pkg/storage"
+)
+
+func TestImageFilterMatch(t *testing.T) {
+	created := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	image := &storage.ImageInfo{
+		Name:       "docker.io/library/nginx:1.27",
+		Repository: "docker.io/library/nginx",
+		Tag:        "1.27",
+		Created:    &created,
+		Labels:     map[string]string{"maintainer": "NGINX", "empty": ""},
+	}
+	dangling := &storage.ImageInfo{
+		Name:       "docker.io/library/nginx@sha256:abc",
+		Repository: "docker.io/library/nginx",
+	}
+
+	earlier := created.Add(-time.Hour)
+	later := created.Add(time.Hour)
+	yes, no := true, false
+
+	tests := []struct {
+		name   string
+		filter imageFilter
+		image  *storage.ImageInfo
+		want   bool
+	}{
+		{"no filters", imageFilter{}, image, true},
+		{"reference matches name", imageFilter{references: []string{"docker.io/library/nginx:1.*"}}, image, true},
+		{"reference matches repository", imageFilter{references: []string{"docker.io/*/nginx"}}, image, true},
+		{"reference doesn't cross slashes", imageFilter{references: []string{"docker.io/*"}}, image, false},
+		{"any reference may match", imageFilter{references: []string{"redis", "*/*/nginx"}}, image, true},
+		{"label key", imageFilter{labels: []string{"maintainer"}}, image, true},
+		{"label key and value", imageFilter{labels: []string{"maintainer=NGINX"}}, image, true},
+		{"label with other value", imageFilter{labels: []string{"maintainer=someone"}}, image, false},
+		{"empty label value", imageFilter{labels: []string{"empty="}}, image, true},
+		{"missing label", imageFilter{labels: []string{"version"}}, image, false},
+		{"every label must match", imageFilter{labels: []string{"maintainer", "version"}}, image, false},
+		{"before", imageFilter{before: &later}, image, true},
+		{"not before", imageFilter{before: &earlier}, image, false},
+		{"before is exclusive", imageFilter{before: &created}, image, false},
+		{"since", imageFilter{since: &earlier}, image, true},
+		{"not since", imageFilter{since: &later}, image, false},
+		{"no creation time", imageFilter{since: &earlier}, dangling, false},
+		{"dangling", imageFilter{dangling: &yes}, dangling, true},
+		{"not dangling", imageFilter{dangling: &yes}, image, false},
+		{"tagged", imageFilter{dangling: &no}, image, true},
+		{"all filters", imageFilter{
+			references: []string{"*/library/nginx"},
+			labels:     []string{"maintainer=NGINX"},
+			since:      &earlier,
+			before:     &later,
+			dangling:   &no,
+		}, image, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.filter.match(tt.image); got != tt.want {
+			t.Errorf("%s: match(%s) = %v, want %v", tt.name, tt.image.Name, got, tt.want)
+		}
+	}
+}
+
+func TestParseImageFilters(t *testing.T) {
+	tests := []struct {
+		filter  string
+		wantErr bool
+	}{
+		{filter: "reference=nginx*"},
+		{filter: "label=maintainer"},
+		{filter: "label=maintainer=NGINX"},
+		{filter: "dangling"},
+		{filter: "dangling=false"},
+		{filter: "reference=[", wantErr: true},
+		{filter: "label=", wantErr: true},
+		{filter: "dangling=maybe", wantErr: true},
+		{filter: "size=10", wantErr: true},
+	}
+
+	// None of these filters needs the store
+	for _, tt := range tests {
+		_, err := parseImageFilters(nil, []string{tt.filter})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseImageFilters(%q) error = %v, want error %v", tt.filter, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/ioworker0/timage/pkg/config"
+	"github.com/ioworker0/timage/pkg/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	loadInput      string
+	loadRepository string
+)
+
+var loadCmd = &cobra.Command{
+	Use:   "load -i [file|dir]",
+	Short: "Load images from an OCI image layout or docker save archive",
+	Long: `Load images from a tar archive or directory written by timage save, docker save,
+skopeo, podman or any other tool producing an OCI image layout or docker save archive.
+
+Every manifest, config and layer is verified and imported into local storage, and
+the images are registered under the names found in the archive. Images the
+archive doesn't name are skipped, unless --repository is given to register them
+as <repository>@<digest>. Images the archive names only by a tag, as skopeo and
+podman do, are registered as <repository>:<tag> and need --repository.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Get storage directory
+		storageDir, err := config.GetStorageDir()
+		if err != nil {
+			cmd.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Create store
+		store, err := storage.NewStore(storageDir)
+		if err != nil {
+			cmd.Printf("Error: Failed to create store: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Keep gc from collecting blobs before the manifest refers to them
+		lock, err := store.LockForWrite()
+		if err != nil {
+			cmd.Printf("Error: Failed to lock store: %v\n", err)
+			os.Exit(1)
+		}
+		defer lock.Unlock()
+
+		result, err := store.LoadArchive(loadInput, loadRepository)
+		if err != nil {
+			cmd.Printf("Error: Failed to load images: %v\n", err)
+			os.Exit(1)
+		}
+
+		for _, image := range result.Images {
+			cmd.Printf("Loaded image: %s\n", image)
+		}
+		for _, skipped := range result.Skipped {
+			cmd.Printf("Skipped untagged image %s (use --repository to load it)\n", skipped)
+		}
+		if len(result.Images)+len(result.Skipped) == 0 {
+			cmd.Println("No images found")
+		}
+	},
+}
+
+func init() {
+	loadCmd.Flags().StringVarP(&loadInput, "input", "i", "", "Tar archive or directory to load")
+	loadCmd.MarkFlagRequired("input")
+	loadCmd.Flags().StringVar(&loadRepository, "repository", "", "Repository for images the archive names only by a tag or not at all, registered as <repository>:<tag> or <repository>@<digest>")
+	rootCmd.AddCommand(loadCmd)
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// multiProgress renders several progress bars at once
+// On a terminal the bars are redrawn in place; otherwise only one line per
+// finished bar is printed so logs stay readable.
+type multiProgress struct {
+	mu          sync.Mutex
+	out         io.Writer
+	interactive bool
+	bars        []*progressBar
+	drawn       int
+	lastRender  time.Time
+}
+
+// progressBar is a single line in a multiProgress display
+type progressBar struct {
+	parent  *multiProgress
+	label   string
+	current int64
+	total   int64
+	status  string
+	done    bool
+}
+
+// newMultiProgress creates a progress display writing to out
+func newMultiProgress(out io.Writer) *multiProgress {
+	interactive := false
+	if f, ok := out.(*os.File); ok {
+		if info, err := f.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			interactive = true
+		}
+	}
+
+	return &multiProgress{
+		out:         out,
+		interactive: interactive,
+	}
+}
+
+// addBar adds a bar to the display
+func (m *multiProgress) addBar(label string, total int64) *progressBar {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bar := &progressBar{
+		parent: m,
+		label:  label,
+		total:  total,
+		status: "Waiting",
+	}
+	m.bars = append(m.bars, bar)
+	m.render(true)
+
+	return bar
+}
+
+// update updates the bar, matching the registry progress callback signature
+func (b *progressBar) update(current, total int64) {
+	b.parent.mu.Lock()
+	defer b.parent.mu.Unlock()
+
+	b.current = current
+	if total > 0 {
+		b.total = total
+	}
+	b.status = "Downloading"
+	b.parent.render(false)
+}
+
+// finish marks the bar as complete with a final status
+func (b *progressBar) finish(status string) {
+	b.parent.mu.Lock()
+	defer b.parent.mu.Unlock()
+
+	b.current = b.total
+	b.status = status
+	b.done = true
+
+	if !b.parent.interactive {
+		fmt.Fprintf(b.parent.out, "%s: %s %s\n", b.label, status, formatBytes(b.total))
+		return
+	}
+	b.parent.render(true)
+}
+
+// fail marks the bar as failed
+func (b *progressBar) fail(err error) {
+	b.parent.mu.Lock()
+	defer b.parent.mu.Unlock()
+
+	b.status = "Failed: " + err.Error()
+	b.done = true
+
+	if !b.parent.interactive {
+		fmt.Fprintf(b.parent.out, "%s: %s\n", b.label, b.status)
+		return
+	}
+	b.parent.render(true)
+}
+
+// render redraws all bars; callers must hold the lock
+func (m *multiProgress) render(force bool) {
+	if !m.interactive {
+		return
+	}
+
+	// Update at most every 100ms to avoid flickering
+	now := time.Now()
+	if !force && now.Sub(m.lastRender) < 100*time.Millisecond {
+		return
+	}
+	m.lastRender = now
+
+	// Move the cursor back to the first bar
+	if m.drawn > 0 {
+		fmt.Fprintf(m.out, "\033[%dA", m.drawn)
+	}
+
+	for _, bar := range m.bars {
+		fmt.Fprintf(m.out, "\033[2K%s\n", bar.line())
+	}
+	m.drawn = len(m.bars)
+}
+
+// line formats a single bar
+func (b *progressBar) line() string {
+	barWidth := 40
+	filled := 0
+	if b.total > 0 {
+		filled = int(float64(barWidth) * float64(b.current) / float64(b.total))
+	}
+	if filled > barWidth {
+		filled = barWidth
+	}
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", barWidth-filled)
+
+	if b.done {
+		return fmt.Sprintf("[%s] %s %s %s", bar, b.label, formatBytes(b.total), b.status)
+	}
+	return fmt.Sprintf("[%s] %s %s / %s %s", bar, b.label,
+		formatBytes(b.current), formatBytes(b.total), b.status)
+}
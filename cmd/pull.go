@@ -1,20 +1,25 @@
 package cmd
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ioworker0/timage/pkg/config"
-	"github.com/ioworker0/timage/pkg/proxy"
 	"github.com/ioworker0/timage/pkg/registry"
 	"github.com/ioworker0/timage/pkg/storage"
 	"github.com/spf13/cobra"
 )
 
+var (
+	pullMaxConcurrentDownloads int
+	pullPlatform               string
+	pullAllPlatforms           bool
+)
+
 var pullCmd = &cobra.Command{
 	Use:   "pull [image]",
 	Short: "Pull an image from a registry",
@@ -22,9 +27,6 @@ var pullCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		imageRef := args[0]
 
-		// Get proxy from flag
-		proxyFlag, _ := cmd.Flags().GetString("proxy")
-
 		// Parse image reference
 		name, tag, registryURL := parseImageRef(imageRef)
 
@@ -43,39 +45,37 @@ var pullCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		// Get proxy URL
-		proxyURL := proxy.GetProxyURL(proxyFlag)
-		if proxyURL == "" {
-			proxyURL = cfg.GetRegistryProxy(registryURL)
-		}
-
-		// Get auth credentials
-		username, password, _ := cfg.GetAuth(registryURL)
-		auth := &registry.AuthConfig{
-			Username: username,
-			Password: password,
-		}
-
 		// Create registry client
-		client, err := registry.NewClient(registryURL, auth, proxyURL)
+		client, err := newRegistryClient(cmd, cfg, registryURL)
 		if err != nil {
 			cmd.Printf("Error: Failed to create registry client: %v\n", err)
 			os.Exit(1)
 		}
 
-		// Get manifest
-		manifest, err := client.GetManifest(name, tag)
+		// Determine the platform to pull for manifest lists
+		platform := registry.DefaultPlatform()
+		if pullPlatform != "" {
+			platform, err = registry.ParsePlatform(pullPlatform)
+			if err != nil {
+				cmd.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		// Get the raw manifest (preserve exact format for storage)
+		// With --all-platforms a manifest list is kept as is, otherwise it is resolved to our platform
+		var manifestRaw []byte
+		var contentType string
+		if pullAllPlatforms {
+			manifestRaw, contentType, err = client.GetManifestRaw(name, tag)
+		} else {
+			manifestRaw, contentType, err = client.GetPlatformManifestRaw(name, tag, platform)
+		}
 		if err != nil {
 			cmd.Printf("Error: Failed to get manifest: %v\n", err)
 			os.Exit(1)
 		}
 
-		cmd.Printf("Manifest: MediaType=%s, SchemaVersion=%d, Layers=%d, Manifests=%d\n",
-			manifest.MediaType, manifest.SchemaVersion, len(manifest.Layers), len(manifest.Manifests))
-		if manifest.Config.Digest != "" {
-			cmd.Printf("Config digest: %s\n", manifest.Config.Digest)
-		}
-
 		// Create storage
 		configDir, err := config.GetConfigDir()
 		if err != nil {
@@ -89,98 +89,85 @@ var pullCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		// Download config blob
-		cmd.Printf("Downloading config...\n")
-		configPath, err := downloadBlobWithProgress(client, store, name, manifest.Config.Digest, "Config")
+		// Keep gc from collecting blobs before the manifest refers to them
+		lock, err := store.LockForWrite()
 		if err != nil {
-			cmd.Printf("Error: Failed to download config: %v\n", err)
+			cmd.Printf("Error: Failed to lock store: %v\n", err)
 			os.Exit(1)
 		}
+		defer lock.Unlock()
+
+		// Collect the config and layers to download, fetching every child manifest of an index
+		var blobs []blobDownload
+		var children []childManifest
+		isIndex := registry.IsManifestList(registry.DetectMediaType(manifestRaw, contentType))
+		if isIndex {
+			var index registry.Manifest
+			if err := json.Unmarshal(manifestRaw, &index); err != nil {
+				cmd.Printf("Error: Failed to parse manifest list: %v\n", err)
+				os.Exit(1)
+			}
 
-		// Save config
-		configData, err := os.ReadFile(configPath)
-		if err != nil {
-			cmd.Printf("Error: Failed to read config: %v\n", err)
-			os.Exit(1)
-		}
+			cmd.Printf("Manifest list: MediaType=%s, Manifests=%d\n",
+				registry.DetectMediaType(manifestRaw, contentType), len(index.Manifests))
+			for _, entry := range index.Manifests {
+				childRaw, _, err := client.GetManifestRaw(name, entry.Digest)
+				if err != nil {
+					cmd.Printf("Error: Failed to get manifest for %s: %v\n", entry.Platform, err)
+					os.Exit(1)
+				}
 
-		if err := store.SaveConfig(imageRef, configData); err != nil {
-			cmd.Printf("Error: Failed to save config: %v\n", err)
-			os.Exit(1)
-		}
+				var child registry.Manifest
+				if err := json.Unmarshal(childRaw, &child); err != nil {
+					cmd.Printf("Error: Failed to parse manifest for %s: %v\n", entry.Platform, err)
+					os.Exit(1)
+				}
+				if registry.IsManifestList(registry.DetectMediaType(childRaw, "")) {
+					cmd.Printf("Error: Nested manifest lists are not supported\n")
+					os.Exit(1)
+				}
 
-		// Download layers
-		cmd.Printf("Downloading layers...\n")
-		for i, layer := range manifest.Layers {
-			layerName := fmt.Sprintf("Layer %d/%d", i+1, len(manifest.Layers))
-			layerPath, err := downloadBlobWithProgress(client, store, name, layer.Digest, layerName)
-			if err != nil {
-				cmd.Printf("Error: Failed to download layer: %v\n", err)
+				cmd.Printf("  %s: %d layers\n", entry.Platform, len(child.Layers))
+				blobs = append(blobs, manifestBlobs(&child, entry.Platform.String()+" ")...)
+				children = append(children, childManifest{digest: entry.Digest, data: childRaw})
+			}
+		} else {
+			var manifest registry.Manifest
+			if err := json.Unmarshal(manifestRaw, &manifest); err != nil {
+				cmd.Printf("Error: Failed to parse manifest: %v\n", err)
 				os.Exit(1)
 			}
 
-			// Save layer
-			if err := store.SaveLayer(imageRef, layer.Digest, layerPath); err != nil {
-				cmd.Printf("Error: Failed to save layer: %v\n", err)
-				os.Exit(1)
+			cmd.Printf("Manifest: MediaType=%s, SchemaVersion=%d, Layers=%d\n",
+				registry.DetectMediaType(manifestRaw, contentType), manifest.SchemaVersion, len(manifest.Layers))
+			if manifest.Config.Digest != "" {
+				cmd.Printf("Config digest: %s\n", manifest.Config.Digest)
 			}
+			blobs = manifestBlobs(&manifest, "")
 		}
 
-		// Get raw manifest for storage (preserve exact format)
-		// First fetch by tag to check if it's a manifest list
-		manifestRaw, contentType, err := client.GetManifestRaw(name, tag)
-		if err != nil {
-			cmd.Printf("Error: Failed to get raw manifest: %v\n", err)
-			os.Exit(1)
+		// Determine download concurrency: flag > config > default
+		maxConcurrent := cfg.GetMaxConcurrentDownloads()
+		if pullMaxConcurrentDownloads > 0 {
+			maxConcurrent = pullMaxConcurrentDownloads
 		}
 
-		// Check if it's a manifest list
-		isManifestList := contentType == "application/vnd.docker.distribution.manifest.list.v2+json" ||
-		                  contentType == "application/vnd.oci.image.index.v1+json"
-
-		if isManifestList {
-			// Parse to find amd64 manifest digest
-			var index struct {
-				Manifests []struct {
-					Digest    string
-					Platform  struct {
-						Architecture string
-						OS           string
-					}
-				}
-			}
-			if err := json.Unmarshal(manifestRaw, &index); err == nil {
-				// Find amd64/linux manifest
-				for _, m := range index.Manifests {
-					if m.Platform.Architecture == "amd64" && m.Platform.OS == "linux" {
-						// Fetch the actual platform-specific manifest
-						manifestRaw, contentType, err = client.GetManifestRaw(name, m.Digest)
-						if err != nil {
-							cmd.Printf("Error: Failed to get platform manifest: %v\n", err)
-							os.Exit(1)
-						}
-						break
-					}
-				}
-			}
+		// Download config and layers
+		cmd.Printf("Downloading %d blobs (up to %d at a time)...\n", len(blobs), maxConcurrent)
+		if err := pullBlobs(cmd, client, store, name, blobs, maxConcurrent); err != nil {
+			cmd.Printf("Error: Failed to download image: %v\n", err)
+			os.Exit(1)
 		}
 
-		// Convert OCI format to Docker format if needed
-		if contentType == "application/vnd.oci.image.manifest.v1+json" {
-			// Replace OCI mediaTypes with Docker mediaTypes
-			manifestRaw = bytes.ReplaceAll(manifestRaw,
-				[]byte("application/vnd.oci.image.config.v1+json"),
-				[]byte("application/vnd.docker.container.image.v1+json"))
-			manifestRaw = bytes.ReplaceAll(manifestRaw,
-				[]byte("application/vnd.oci.image.layer.v1.tar+gzip"),
-				[]byte("application/vnd.docker.image.rootfs.diff.tar.gzip"))
-			// Also update the top-level mediaType
-			manifestRaw = bytes.ReplaceAll(manifestRaw,
-				[]byte("application/vnd.oci.image.manifest.v1+json"),
-				[]byte("application/vnd.docker.distribution.manifest.v2+json"))
+		// Save child manifests before the index so the index never refers to missing data
+		for _, child := range children {
+			if err := store.SaveChildManifest(child.digest, child.data); err != nil {
+				cmd.Printf("Error: Failed to save manifest: %v\n", err)
+				os.Exit(1)
+			}
 		}
 
-		// Save manifest
+		// Save manifest byte for byte so its digest matches the registry's
 		if err := store.SaveManifestRaw(imageRef, manifestRaw); err != nil {
 			cmd.Printf("Error: Failed to save manifest: %v\n", err)
 			os.Exit(1)
@@ -191,6 +178,12 @@ var pullCmd = &cobra.Command{
 }
 
 func init() {
+	pullCmd.Flags().IntVar(&pullMaxConcurrentDownloads, "max-concurrent-downloads", 0,
+		fmt.Sprintf("Maximum number of layers to download in parallel (default from config, or %d)", config.DefaultMaxConcurrentDownloads))
+	pullCmd.Flags().StringVar(&pullPlatform, "platform", "",
+		fmt.Sprintf("Platform to pull from multi-arch images, as os/arch[/variant] (default %s)", registry.DefaultPlatform()))
+	pullCmd.Flags().BoolVar(&pullAllPlatforms, "all-platforms", false, "Pull the whole manifest list or image index with the images for every platform")
+	pullCmd.MarkFlagsMutuallyExclusive("platform", "all-platforms")
 	rootCmd.AddCommand(pullCmd)
 }
 
@@ -209,8 +202,14 @@ func parseImageRef(imageRef string) (name, tag, registry string) {
 		}
 	}
 
-	// Parse tag
-	if idx := strings.Index(imageRef, ":"); idx != -1 {
+	// Parse digest or tag; a digest wins over a tag given with it
+	if idx := strings.Index(imageRef, "@"); idx != -1 {
+		tag = imageRef[idx+1:]
+		name = imageRef[:idx]
+		if i := strings.Index(name, ":"); i != -1 {
+			name = name[:i]
+		}
+	} else if idx := strings.Index(imageRef, ":"); idx != -1 {
 		tag = imageRef[idx+1:]
 		name = imageRef[:idx]
 	} else {
@@ -225,81 +224,141 @@ func parseImageRef(imageRef string) (name, tag, registry string) {
 	return name, tag, registry
 }
 
-func downloadBlobWithProgress(client *registry.Client, store *storage.Store, name, digest, label string) (string, error) {
-	// Validate digest
-	if digest == "" {
-		return "", fmt.Errorf("empty digest")
+// childManifest is a platform manifest of an index fetched with --all-platforms
+type childManifest struct {
+	digest string
+	data   []byte
+}
+
+// manifestBlobs returns the config and layers of a manifest for the pull pipeline
+func manifestBlobs(manifest *registry.Manifest, labelPrefix string) []blobDownload {
+	blobs := []blobDownload{{
+		label:  labelPrefix + "Config",
+		digest: manifest.Config.Digest,
+		size:   manifest.Config.Size,
+	}}
+	for i, layer := range manifest.Layers {
+		blobs = append(blobs, blobDownload{
+			label:  fmt.Sprintf("%sLayer %d/%d", labelPrefix, i+1, len(manifest.Layers)),
+			digest: layer.Digest,
+			size:   layer.Size,
+		})
 	}
+	return blobs
+}
 
-	// For simplicity, download to temp file first
-	tempPath := fmt.Sprintf("/tmp/timage-%s.tmp", digest[:12])
+// blobDownload is a blob fetched by the pull pipeline
+type blobDownload struct {
+	label  string
+	digest string
+	size   int64
+}
 
-	// Create progress tracker
-	progress := &progressTracker{
-		label:  label,
-		digest: digest,
-		start:  time.Now(),
+// pullBlobs downloads blobs into the shared blob store, at most maxConcurrent at a time
+// Blobs already in the store are skipped. The first error stops new downloads from
+// starting and is returned once the running ones have finished.
+func pullBlobs(cmd *cobra.Command, client *registry.Client, store *storage.Store, name string, blobs []blobDownload, maxConcurrent int) error {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
 	}
 
-	if err := client.DownloadBlobWithProgress(name, digest, tempPath, progress.update); err != nil {
-		return "", err
-	}
+	display := newMultiProgress(cmd.OutOrStderr())
 
-	// Print completion
-	progress.finish()
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		seen     = make(map[string]bool)
+		slots    = make(chan struct{}, maxConcurrent)
+	)
 
-	return tempPath, nil
-}
+	for _, blob := range blobs {
+		// The same layer can appear more than once in a manifest
+		if seen[blob.digest] {
+			continue
+		}
+		seen[blob.digest] = true
+
+		bar := display.addBar(fmt.Sprintf("%s %s", blob.label, shortDigest(blob.digest)), blob.size)
+
+		if store.HasBlob(blob.digest) {
+			bar.finish("Already exists")
+			continue
+		}
+
+		slots <- struct{}{}
+
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			<-slots
+			break
+		}
+
+		wg.Add(1)
+		go func(blob blobDownload, bar *progressBar) {
+			defer wg.Done()
+			defer func() { <-slots }()
 
-// progressTracker tracks download progress
-type progressTracker struct {
-	label      string
-	digest     string
-	downloaded int64
-	total      int64
-	start      time.Time
-	lastUpdate time.Time
+			if err := pullBlob(client, store, name, blob.digest, bar); err != nil {
+				bar.fail(err)
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("%s: %w", blob.label, err)
+				}
+				mu.Unlock()
+				return
+			}
+			bar.finish("Pull complete")
+		}(blob, bar)
+	}
+
+	wg.Wait()
+	return firstErr
 }
 
-// update updates the progress
-func (p *progressTracker) update(downloaded, total int64) {
-	p.downloaded = downloaded
-	p.total = total
-	now := time.Now()
+// pullBlob downloads a single blob and moves it into the shared blob store
+// Interrupted downloads are kept in the store and resumed on the next pull. Pulls
+// of the same blob by other processes wait for each other.
+func pullBlob(client *registry.Client, store *storage.Store, name, digest string, bar *progressBar) error {
+	// Validate digest
+	if digest == "" {
+		return fmt.Errorf("empty digest")
+	}
+
+	partialPath, err := store.GetPartialPath(digest)
+	if err != nil {
+		return err
+	}
 
-	// Update at most every 100ms to avoid flickering
-	if now.Sub(p.lastUpdate) < 100*time.Millisecond && downloaded < total {
-		return
+	lock, err := store.LockPartial(digest)
+	if err != nil {
+		return err
 	}
-	p.lastUpdate = now
+	defer lock.Unlock()
 
-	// Calculate progress
-	percent := float64(downloaded) / float64(total) * 100
+	// Another process may have stored the blob while we waited
+	if store.HasBlob(digest) {
+		return os.Remove(partialPath)
+	}
 
-	// Build progress bar
-	barWidth := 40
-	filled := int(float64(barWidth) * percent / 100)
-	bar := strings.Repeat("█", filled) + strings.Repeat("░", barWidth-filled)
+	if err := client.ResumeBlobDownload(name, digest, partialPath, bar.update); err != nil {
+		return err
+	}
 
-	// Print progress
-	fmt.Printf("\r[%s] %s %s",
-		bar,
-		p.label,
-		formatBytes(total),
-	)
+	return store.ImportBlob(digest, partialPath)
 }
 
-// finish prints the completion message
-func (p *progressTracker) finish() {
-	// Build complete progress bar
-	barWidth := 40
-	bar := strings.Repeat("█", barWidth)
-
-	fmt.Printf("\r[%s] %s %s\n",
-		bar,
-		p.label,
-		formatBytes(p.total),
-	)
+// shortDigest returns the first 12 hex characters of a digest
+func shortDigest(digest string) string {
+	if idx := strings.Index(digest, ":"); idx != -1 {
+		digest = digest[idx+1:]
+	}
+	if len(digest) > 12 {
+		digest = digest[:12]
+	}
+	return digest
 }
 
 // formatBytes formats a byte size
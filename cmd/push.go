@@ -2,11 +2,10 @@ package cmd
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
-	"strings"
 
 	"github.com/ioworker0/timage/pkg/config"
-	"github.com/ioworker0/timage/pkg/proxy"
 	"github.com/ioworker0/timage/pkg/registry"
 	"github.com/ioworker0/timage/pkg/storage"
 	"github.com/spf13/cobra"
@@ -19,9 +18,6 @@ var pushCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		imageRef := args[0]
 
-		// Get proxy from flag
-		proxyFlag, _ := cmd.Flags().GetString("proxy")
-
 		// Parse image reference
 		name, tag, registryURL := parseImageRef(imageRef)
 
@@ -46,13 +42,6 @@ var pushCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		// Load manifest
-		manifest, err := store.LoadManifest(imageRef)
-		if err != nil {
-			cmd.Printf("Error: Failed to load manifest: %v\n", err)
-			os.Exit(1)
-		}
-
 		// Get auth from config
 		configDir, err := config.GetConfigDir()
 		if err != nil {
@@ -66,69 +55,20 @@ var pushCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		// Get proxy URL
-		proxyURL := proxy.GetProxyURL(proxyFlag)
-		if proxyURL == "" {
-			proxyURL = cfg.GetRegistryProxy(registryURL)
-		}
-
-		// Get auth credentials
-		username, password, _ := cfg.GetAuth(registryURL)
-		auth := &registry.AuthConfig{
-			Username: username,
-			Password: password,
-		}
-
 		// Create registry client
-		client, err := registry.NewClient(registryURL, auth, proxyURL)
+		client, err := newRegistryClient(cmd, cfg, registryURL)
 		if err != nil {
 			cmd.Printf("Error: Failed to create registry client: %v\n", err)
 			os.Exit(1)
 		}
 
-		// Upload config blob
-		cmd.Printf("Uploading config...\n")
-		configPath := store.GetConfigPath(imageRef)
-
-		// Try to upload config blob (Harbor will check if it exists)
-		err = client.UploadBlob(name, manifest.Config.Digest, configPath)
+		// Local images from other repositories on the same registry tell us where blobs can be mounted from
+		blobRefs, err := store.BlobReferences()
 		if err != nil {
-			// Check if it's a "blob already exists" error
-			if strings.Contains(err.Error(), "BLOB_UPLOAD_INVALID") ||
-				strings.Contains(err.Error(), "already exists") ||
-				strings.Contains(err.Error(), "exist blob") {
-				cmd.Printf("  Config already exists, skipping\n")
-			} else {
-				cmd.Printf("Error: Failed to upload config: %v\n", err)
-				os.Exit(1)
-			}
-		}
-
-		// Upload layers
-		cmd.Printf("Uploading %d layers...\n", len(manifest.Layers))
-		for i, layer := range manifest.Layers {
-			layerPath := store.GetLayerPath(imageRef, layer.Digest)
-
-			cmd.Printf("  [%d/%d] %s\n", i+1, len(manifest.Layers), layer.Digest[:12])
-
-			// Try to upload layer blob (Harbor will check if it exists)
-			err = client.UploadBlob(name, layer.Digest, layerPath)
-			if err != nil {
-				// Check if it's a "blob already exists" error
-				if strings.Contains(err.Error(), "BLOB_UPLOAD_INVALID") ||
-					strings.Contains(err.Error(), "already exists") ||
-					strings.Contains(err.Error(), "exist blob") {
-					cmd.Printf("    Already exists, skipping\n")
-				} else {
-					cmd.Printf("Error: Failed to upload layer: %v\n", err)
-					os.Exit(1)
-				}
-			}
+			cmd.Printf("Error: Failed to read local images: %v\n", err)
+			os.Exit(1)
 		}
 
-		// Upload manifest
-		cmd.Printf("Uploading manifest...\n")
-
 		// Load raw manifest (preserve exact format from pull)
 		manifestData, err := store.LoadManifestRaw(imageRef)
 		if err != nil {
@@ -136,39 +76,57 @@ var pushCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		// Parse and clean the manifest
-		var manifestObj map[string]interface{}
-		if err := json.Unmarshal(manifestData, &manifestObj); err != nil {
+		// A manifest list or index is pushed with every child image, then the index itself unchanged
+		if mediaType := registry.DetectMediaType(manifestData, ""); registry.IsManifestList(mediaType) {
+			if err := pushIndex(cmd, client, store, name, registryURL, manifestData, blobRefs); err != nil {
+				cmd.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			cmd.Printf("Uploading manifest list...\n")
+			if err := client.PutManifest(name, tag, manifestData, mediaType); err != nil {
+				cmd.Printf("Error: Failed to upload manifest list: %v\n", err)
+				os.Exit(1)
+			}
+
+			cmd.Printf("\nSuccessfully pushed %s\n", imageRef)
+			return
+		}
+
+		var manifest registry.Manifest
+		if err := json.Unmarshal(manifestData, &manifest); err != nil {
 			cmd.Printf("Error: Failed to parse manifest: %v\n", err)
 			os.Exit(1)
 		}
 
-		// Get content type
-		contentType, _ := manifestObj["mediaType"].(string)
-
-		// Check if it's a manifest list/index
-		if contentType == "application/vnd.docker.distribution.manifest.list.v2+json" ||
-			contentType == "application/vnd.oci.image.index.v1+json" {
-			cmd.Printf("Error: Cannot push manifest list/index. Please pull and push individual platform manifests.\n")
+		// Upload config and layers
+		if err := pushManifestBlobs(cmd, client, store, name, registryURL, &manifest, blobRefs); err != nil {
+			cmd.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
 
-		// Remove 'manifests' field if it exists (even if null) to ensure it's a pure manifest
-		// Some registries reject manifests with a 'manifests' field during docker build
-		delete(manifestObj, "manifests")
+		// Upload manifest
+		cmd.Printf("Uploading manifest...\n")
 
-		// Ensure it's a proper manifest v2
-		if contentType == "" {
-			manifestObj["mediaType"] = "application/vnd.docker.distribution.manifest.v2+json"
-			contentType = "application/vnd.docker.distribution.manifest.v2+json"
-		}
+		// Push the manifest bytes as stored so its digest matches the pulled image
+		contentType := registry.DetectMediaType(manifestData, "")
 
-		// Re-marshal the cleaned manifest
-		manifestData, err = json.Marshal(manifestObj)
-		if err != nil {
-			cmd.Printf("Error: Failed to marshal manifest: %v\n", err)
+		// Manifests stored by older versions may carry an empty 'manifests' field, which some
+		// registries reject. Removing it re-encodes the manifest, so its digest changes.
+		var manifestObj map[string]json.RawMessage
+		if err := json.Unmarshal(manifestData, &manifestObj); err != nil {
+			cmd.Printf("Error: Failed to parse manifest: %v\n", err)
 			os.Exit(1)
 		}
+		if _, ok := manifestObj["manifests"]; ok {
+			delete(manifestObj, "manifests")
+			manifestData, err = json.Marshal(manifestObj)
+			if err != nil {
+				cmd.Printf("Error: Failed to marshal manifest: %v\n", err)
+				os.Exit(1)
+			}
+			cmd.Printf("  Removed the legacy 'manifests' field; the pushed digest is %s\n", registry.ComputeDigest(manifestData))
+		}
 
 		// Upload manifest
 		if err := client.PutManifest(name, tag, manifestData, contentType); err != nil {
@@ -180,6 +138,111 @@ var pushCmd = &cobra.Command{
 	},
 }
 
+// pushIndex pushes every child image of a manifest list or index by digest
+func pushIndex(cmd *cobra.Command, client *registry.Client, store *storage.Store, name, registryURL string, indexData []byte, blobRefs map[string][]string) error {
+	var index registry.Manifest
+	if err := json.Unmarshal(indexData, &index); err != nil {
+		return fmt.Errorf("failed to parse manifest list: %w", err)
+	}
+
+	for i, entry := range index.Manifests {
+		cmd.Printf("Pushing %s [%d/%d]...\n", entry.Platform, i+1, len(index.Manifests))
+
+		childData, err := store.LoadChildManifestRaw(entry.Digest)
+		if err != nil {
+			return err
+		}
+
+		var child registry.Manifest
+		if err := json.Unmarshal(childData, &child); err != nil {
+			return fmt.Errorf("failed to parse manifest %s: %w", entry.Digest, err)
+		}
+
+		if err := pushManifestBlobs(cmd, client, store, name, registryURL, &child, blobRefs); err != nil {
+			return err
+		}
+
+		// Push by digest; the exact bytes are needed for the index to stay valid
+		mediaType := entry.MediaType
+		if mediaType == "" {
+			mediaType = registry.DetectMediaType(childData, "")
+		}
+		if err := client.PutManifest(name, entry.Digest, childData, mediaType); err != nil {
+			return fmt.Errorf("failed to upload manifest for %s: %w", entry.Platform, err)
+		}
+	}
+
+	return nil
+}
+
+// pushManifestBlobs pushes the config and layers of a manifest
+func pushManifestBlobs(cmd *cobra.Command, client *registry.Client, store *storage.Store, name, registryURL string, manifest *registry.Manifest, blobRefs map[string][]string) error {
+	// Upload config blob
+	cmd.Printf("Uploading config...\n")
+	if err := pushBlob(cmd, client, store, name, manifest.Config.Digest, mountSources(blobRefs[manifest.Config.Digest], registryURL, name)); err != nil {
+		return fmt.Errorf("failed to upload config: %w", err)
+	}
+
+	// Upload layers
+	cmd.Printf("Uploading %d layers...\n", len(manifest.Layers))
+	for i, layer := range manifest.Layers {
+		cmd.Printf("  [%d/%d] %s\n", i+1, len(manifest.Layers), shortDigest(layer.Digest))
+
+		if err := pushBlob(cmd, client, store, name, layer.Digest, mountSources(blobRefs[layer.Digest], registryURL, name)); err != nil {
+			return fmt.Errorf("failed to upload layer: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// pushBlob makes a blob available in the target repository
+// It skips blobs the registry already has, tries to mount the blob from other
+// repositories on the same registry, and only uploads it as a last resort.
+func pushBlob(cmd *cobra.Command, client *registry.Client, store *storage.Store, name, digest string, mountFrom []string) error {
+	// Check if the blob already exists in the target repository
+	exists, err := client.CheckBlob(name, digest)
+	if err == nil && exists {
+		cmd.Printf("    Already exists, skipping\n")
+		return nil
+	}
+
+	// Try a cross-repository mount
+	for _, from := range mountFrom {
+		mounted, err := client.MountBlob(name, digest, from)
+		if err == nil && mounted {
+			cmd.Printf("    Mounted from %s\n", from)
+			return nil
+		}
+	}
+
+	blobPath, err := store.GetBlobPath(digest)
+	if err != nil {
+		return err
+	}
+
+	return client.UploadBlob(name, digest, blobPath)
+}
+
+// mountSources returns the repositories on registryURL, other than name, that
+// local images referencing a blob were pulled from or pushed to
+func mountSources(images []string, registryURL, name string) []string {
+	var sources []string
+	seen := make(map[string]bool)
+
+	for _, image := range images {
+		imageName, _, imageRegistry := parseImageRef(image)
+		if imageRegistry != registryURL || imageName == name || seen[imageName] {
+			continue
+		}
+		seen[imageName] = true
+		sources = append(sources, imageName)
+	}
+
+	return sources
+}
+
 func init() {
+	pushCmd.Flags().String("chunk-size", "", "Upload blobs in chunks of this size, e.g. 64MB; 0 forces single-request uploads (default from registry config)")
 	rootCmd.AddCommand(pushCmd)
 }
@@ -2,19 +2,32 @@ package cmd
 
 import (
 	"os"
+	"strings"
 
 	"github.com/ioworker0/timage/pkg/config"
 	"github.com/ioworker0/timage/pkg/storage"
 	"github.com/spf13/cobra"
 )
 
+var rmRemote bool
+
 var rmCmd = &cobra.Command{
 	Use:   "rm [image]",
-	Short: "Remove a local image",
-	Args:  cobra.ExactArgs(1),
+	Short: "Remove a local image, or a tag or manifest in a registry",
+	Long: `Remove a local image.
+
+With --remote the image is deleted from its registry instead. A tag is resolved to
+the digest of its manifest and the manifest is deleted, which removes every tag
+referring to the same manifest. Images can also be deleted by digest (name@sha256:...).`,
+	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		imageRef := args[0]
 
+		if rmRemote {
+			removeRemote(cmd, imageRef)
+			return
+		}
+
 		// Get storage directory
 		storageDir, err := config.GetStorageDir()
 		if err != nil {
@@ -42,9 +55,54 @@ var rmCmd = &cobra.Command{
 		}
 
 		cmd.Printf("Removed: %s\n", imageRef)
+		cmd.Printf("Run 'timage gc' to free space used by layers no other image references\n")
 	},
 }
 
 func init() {
+	rmCmd.Flags().BoolVar(&rmRemote, "remote", false, "Delete the image from its registry instead of local storage")
 	rootCmd.AddCommand(rmCmd)
 }
+
+// removeRemote deletes the manifest an image reference points to from its registry
+func removeRemote(cmd *cobra.Command, imageRef string) {
+	name, reference, registryURL := parseImageRef(imageRef)
+
+	// Get auth from config
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		cmd.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	cfg, err := config.NewManager(configDir)
+	if err != nil {
+		cmd.Printf("Error: Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Create registry client
+	client, err := newRegistryClient(cmd, cfg, registryURL)
+	if err != nil {
+		cmd.Printf("Error: Failed to create registry client: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Registries only delete by digest, so resolve tags first
+	digest := reference
+	if !strings.Contains(imageRef, "@") {
+		digest, err = client.ResolveDeleteDigest(name, reference)
+		if err != nil {
+			cmd.Printf("Error: Failed to resolve %s: %v\n", imageRef, err)
+			os.Exit(1)
+		}
+	}
+
+	if err := client.DeleteManifest(name, digest); err != nil {
+		cmd.Printf("Error: Failed to delete %s: %v\n", imageRef, err)
+		os.Exit(1)
+	}
+
+	cmd.Printf("Deleted: %s (%s)\n", imageRef, digest)
+	cmd.Printf("Tags referring to the same manifest were deleted too; run the registry's garbage collection to free the blobs\n")
+}
@@ -23,4 +23,6 @@ func Execute() {
 func init() {
 	// 全局 flag
 	rootCmd.PersistentFlags().StringP("proxy", "x", "", "Proxy URL (e.g., http://127.0.0.1:7890, socks5://127.0.0.1:1080)")
+	rootCmd.PersistentFlags().Int("retry-attempts", 0, "Maximum attempts per registry request, including the first (default from config, or 5)")
+	rootCmd.PersistentFlags().Duration("retry-backoff", 0, "Wait before the first retry, doubled on every attempt (default from config, or 500ms)")
 }
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ioworker0/timage/pkg/config"
+	"github.com/ioworker0/timage/pkg/registry"
+	"github.com/ioworker0/timage/pkg/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	saveFormat   string
+	saveOutput   string
+	savePlatform string
+)
+
+var saveCmd = &cobra.Command{
+	Use:   "save -o [output] [image...]",
+	Short: "Save local images to a tar archive or directory",
+	Long: `Save one or more local images as an OCI image layout or a docker save archive.
+
+With --format oci an output path ending in .tar is written as a tarball; any other
+path is written as an OCI image layout directory. Each image is listed in index.json
+with its name in the org.opencontainers.image.ref.name annotation.
+
+With --format docker the archive has the layout written by docker save and can be
+read by docker load. Layers are stored decompressed, and for multi-arch images only
+the image for --platform is included.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		format, err := registry.ParseFormat(saveFormat)
+		if err != nil {
+			cmd.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		platform := registry.DefaultPlatform()
+		if savePlatform != "" {
+			platform, err = registry.ParsePlatform(savePlatform)
+			if err != nil {
+				cmd.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		// Get storage directory
+		storageDir, err := config.GetStorageDir()
+		if err != nil {
+			cmd.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Create store
+		store, err := storage.NewStore(storageDir)
+		if err != nil {
+			cmd.Printf("Error: Failed to create store: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Check that every image exists before writing anything
+		for _, imageRef := range args {
+			if !store.ImageExists(imageRef) {
+				cmd.Printf("Error: Image '%s' not found\n", imageRef)
+				os.Exit(1)
+			}
+		}
+
+		export := func(out storage.ArchiveWriter) error {
+			if format == registry.FormatDocker {
+				return store.ExportDockerArchive(out, args, platform)
+			}
+			return store.ExportOCILayout(out, args)
+		}
+
+		// docker load only reads tarballs
+		if format == registry.FormatDocker || strings.HasSuffix(saveOutput, ".tar") {
+			err = saveTar(saveOutput, export)
+		} else {
+			err = saveDir(saveOutput, export)
+		}
+		if err != nil {
+			cmd.Printf("Error: Failed to save images: %v\n", err)
+			os.Exit(1)
+		}
+
+		for _, imageRef := range args {
+			cmd.Printf("Saved: %s\n", imageRef)
+		}
+		cmd.Printf("Wrote %s\n", saveOutput)
+	},
+}
+
+func init() {
+	saveCmd.Flags().StringVar(&saveFormat, "format", "oci", "Archive format: oci or docker")
+	saveCmd.Flags().StringVarP(&saveOutput, "output", "o", "", "Output tar file (*.tar) or directory (oci only)")
+	saveCmd.Flags().StringVar(&savePlatform, "platform", "",
+		fmt.Sprintf("Platform to save from multi-arch images with --format docker, as os/arch[/variant] (default %s)", registry.DefaultPlatform()))
+	saveCmd.MarkFlagRequired("output")
+	rootCmd.AddCommand(saveCmd)
+}
+
+// saveTar writes an archive to a temp file next to path and renames it in place,
+// so an interrupted save never leaves a truncated tarball behind
+func saveTar(path string, export func(storage.ArchiveWriter) error) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), ".timage-save-*")
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+
+	out := storage.NewTarArchive(tmpFile)
+	err = export(out)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, 0644)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	return nil
+}
+
+// saveDir writes an archive as files below a directory
+func saveDir(dir string, export func(storage.ArchiveWriter) error) error {
+	out, err := storage.NewDirArchive(dir)
+	if err != nil {
+		return err
+	}
+
+	if err := export(out); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ioworker0/timage/pkg/config"
+	"github.com/ioworker0/timage/pkg/registry"
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncFile    string
+	syncDryRun  bool
+	syncVerbose bool
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync -f [sync.yaml]",
+	Short: "Mirror repositories between registries as described in a sync file",
+	Long: `Mirror repositories between registries as described in a sync file.
+
+Every selected tag is copied directly from the source registry to the destination,
+like timage copy. Tags the destination already has are skipped and blobs it already
+has are not copied again, so sync can be rerun at any time to pick up new tags or
+finish an interrupted run. The command exits with a non-zero status if any image
+failed.
+
+Example sync file:
+
+  destination: harbor.example.com/mirror
+  platforms: [linux/amd64, linux/arm64]
+  images:
+    - source: docker.io/library/nginx
+      tags:
+        semver: ">=1.24"
+        latest: 3
+    - source: docker.io/library/redis
+      tags:
+        exact: ["7.2", "7.4"]
+        regex: "^7\\.4\\.[0-9]+-alpine$"
+    - source: quay.io/prometheus/prometheus
+      destination: harbor.example.com/monitoring
+      repository: prometheus`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		file, err := config.LoadSyncFile(syncFile)
+		if err != nil {
+			cmd.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Get auth and proxy settings from config
+		configDir, err := config.GetConfigDir()
+		if err != nil {
+			cmd.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		cfg, err := config.NewManager(configDir)
+		if err != nil {
+			cmd.Printf("Error: Failed to load config: %v\n", err)
+			os.Exit(1)
+		}
+
+		s := &syncer{cmd: cmd, cfg: cfg, clients: make(map[string]*registry.Client)}
+		for _, image := range file.Images {
+			s.syncImage(image)
+		}
+
+		if syncDryRun {
+			cmd.Printf("\nWould copy: %d, up to date: %d, failed: %d\n", s.copied, s.upToDate, s.failed)
+		} else {
+			cmd.Printf("\nCopied: %d, up to date: %d, failed: %d\n", s.copied, s.upToDate, s.failed)
+		}
+		if s.failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	syncCmd.Flags().StringVarP(&syncFile, "file", "f", "", "Sync file describing the repositories to mirror")
+	syncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "Only show which tags are up to date and which would be mirrored")
+	syncCmd.Flags().BoolVarP(&syncVerbose, "verbose", "v", false, "Show the progress of every blob")
+	syncCmd.MarkFlagRequired("file")
+	rootCmd.AddCommand(syncCmd)
+}
+
+// syncer mirrors the images of a sync file and keeps count of the results
+type syncer struct {
+	cmd     *cobra.Command
+	cfg     *config.Manager
+	clients map[string]*registry.Client
+
+	copied   int
+	upToDate int
+	failed   int
+}
+
+// client returns the registry client for a registry, creating it on first use
+func (s *syncer) client(registryURL string) (*registry.Client, error) {
+	if client, ok := s.clients[registryURL]; ok {
+		return client, nil
+	}
+
+	client, err := newRegistryClient(s.cmd, s.cfg, registryURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create registry client for %s: %w", registryURL, err)
+	}
+	s.clients[registryURL] = client
+	return client, nil
+}
+
+// syncImage mirrors the selected tags of one source repository
+func (s *syncer) syncImage(image config.SyncImage) {
+	srcName, _, srcRegistry := parseImageRef(image.Source)
+
+	repository := image.Repository
+	if repository == "" {
+		repository = srcName
+	}
+	dstRepo := strings.TrimSuffix(image.Destination, "/") + "/" + repository
+	dstName, _, dstRegistry := parseImageRef(dstRepo)
+
+	s.cmd.Printf("%s -> %s\n", image.Source, dstRepo)
+
+	tags, platforms, c, err := s.prepare(image, srcName, srcRegistry, dstName, dstRegistry)
+	if err != nil {
+		s.cmd.Printf("  Failed: %v\n", err)
+		s.failed++
+		return
+	}
+	if len(tags) == 0 {
+		s.cmd.Printf("  No matching tags\n")
+		return
+	}
+
+	for _, tag := range tags {
+		if syncDryRun {
+			digest, upToDate, err := c.checkTag(tag, tag, platforms)
+			switch {
+			case err != nil:
+				s.cmd.Printf("  %s: failed: %v\n", tag, err)
+				s.failed++
+			case upToDate:
+				s.cmd.Printf("  %s: up to date\n", tag)
+				s.upToDate++
+			default:
+				s.cmd.Printf("  %s: would sync %s\n", tag, shortDigest(digest))
+				s.copied++
+			}
+			continue
+		}
+
+		digest, copied, err := c.copyTag(tag, tag, platforms)
+		switch {
+		case err != nil:
+			s.cmd.Printf("  %s: failed: %v\n", tag, err)
+			s.failed++
+		case copied:
+			s.cmd.Printf("  %s: copied %s\n", tag, shortDigest(digest))
+			s.copied++
+		default:
+			s.cmd.Printf("  %s: up to date\n", tag)
+			s.upToDate++
+		}
+	}
+}
+
+// prepare resolves the tags, platforms and registry clients for a source repository
+func (s *syncer) prepare(image config.SyncImage, srcName, srcRegistry, dstName, dstRegistry string) ([]string, []registry.Platform, *imageCopier, error) {
+	var platforms []registry.Platform
+	for _, p := range image.Platforms {
+		platform, err := registry.ParsePlatform(p)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		platforms = append(platforms, platform)
+	}
+
+	filter := &registry.TagFilter{
+		Exact:  image.Tags.Exact,
+		Regex:  image.Tags.Regex,
+		Semver: image.Tags.Semver,
+		Latest: image.Tags.Latest,
+	}
+	if err := filter.Compile(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	src, err := s.client(srcRegistry)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	dst, err := s.client(dstRegistry)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var available []string
+	if filter.NeedsTagList() {
+		available, err = src.ListTags(srcName)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	tags, err := filter.Apply(available)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	c := &imageCopier{
+		cmd:          s.cmd,
+		src:          src,
+		srcName:      srcName,
+		dst:          dst,
+		dstName:      dstName,
+		sameRegistry: srcRegistry == dstRegistry,
+		verbose:      syncVerbose,
+	}
+
+	return tags, platforms, c, nil
+}
@@ -1,24 +1,34 @@
 package cmd
 
 import (
-	"io"
 	"os"
-	"path/filepath"
-	"strings"
 
 	"github.com/ioworker0/timage/pkg/config"
 	"github.com/ioworker0/timage/pkg/storage"
 	"github.com/spf13/cobra"
 )
 
+var tagRemote bool
+
 var tagCmd = &cobra.Command{
 	Use:   "tag [source] [target]",
 	Short: "Tag an image",
-	Args:  cobra.ExactArgs(2),
+	Long: `Tag a local image.
+
+With --remote the tag is created in the registry instead, without pulling the image:
+the source manifest is pushed unchanged under the target tag. The target may be in
+another repository on the same registry, in which case blobs are mounted across
+repositories rather than uploaded.`,
+	Args: cobra.ExactArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
 		source := args[0]
 		target := args[1]
 
+		if tagRemote {
+			tagRemoteImage(cmd, source, target)
+			return
+		}
+
 		// Get storage directory
 		storageDir, err := config.GetStorageDir()
 		if err != nil {
@@ -39,81 +49,66 @@ var tagCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		// Copy manifest
-		manifest, err := store.LoadManifest(source)
-		if err != nil {
-			cmd.Printf("Error: Failed to load manifest: %v\n", err)
-			os.Exit(1)
-		}
-
-		if err := store.SaveManifest(target, manifest); err != nil {
-			cmd.Printf("Error: Failed to save manifest: %v\n", err)
-			os.Exit(1)
-		}
-
-		// Copy config
-		configData, err := store.LoadConfig(source)
-		if err != nil {
-			cmd.Printf("Error: Failed to load config: %v\n", err)
-			os.Exit(1)
-		}
-
-		if err := store.SaveConfig(target, configData); err != nil {
-			cmd.Printf("Error: Failed to save config: %v\n", err)
+		// Copy manifest (config and layers are shared through the blob store)
+		if err := store.TagImage(source, target); err != nil {
+			cmd.Printf("Error: Failed to tag image: %v\n", err)
 			os.Exit(1)
 		}
 
-		// Copy layers directory using the same encoding as layout.go
-		sourceLayersDir := filepath.Join(storageDir, "images",
-			strings.ReplaceAll(strings.ReplaceAll(source, ":", "_COLON_"), "/", "_SLASH_"), "layers")
-		targetLayersDir := filepath.Join(storageDir, "images",
-			strings.ReplaceAll(strings.ReplaceAll(target, ":", "_COLON_"), "/", "_SLASH_"), "layers")
-
-		if err := os.MkdirAll(filepath.Dir(targetLayersDir), 0755); err != nil {
-			cmd.Printf("Error: Failed to create target directory: %v\n", err)
-			os.Exit(1)
-		}
-
-		// Copy all layer files
-		files, err := os.ReadDir(sourceLayersDir)
-		if err == nil {
-			for _, file := range files {
-				if !file.IsDir() {
-					srcFile := filepath.Join(sourceLayersDir, file.Name())
-					dstFile := filepath.Join(targetLayersDir, file.Name())
-
-					if err := copyFile(srcFile, dstFile); err != nil {
-						cmd.Printf("Error: Failed to copy layer: %v\n", err)
-						os.Exit(1)
-					}
-				}
-			}
-		}
-
 		cmd.Printf("Tagged %s as %s\n", source, target)
 	},
 }
 
 func init() {
+	tagCmd.Flags().BoolVar(&tagRemote, "remote", false, "Tag the image in its registry instead of local storage")
 	rootCmd.AddCommand(tagCmd)
 }
 
-func copyFile(src, dst string) error {
-	sourceFile, err := os.Open(src)
+// tagRemoteImage pushes the manifest of source under the target tag in the same registry
+func tagRemoteImage(cmd *cobra.Command, source, target string) {
+	srcName, srcTag, srcRegistry := parseImageRef(source)
+	dstName, dstTag, dstRegistry := parseImageRef(target)
+
+	if srcRegistry != dstRegistry {
+		cmd.Printf("Error: Source and target are in different registries; use 'timage copy' instead\n")
+		os.Exit(1)
+	}
+
+	// Get auth from config
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		cmd.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	cfg, err := config.NewManager(configDir)
 	if err != nil {
-		return err
+		cmd.Printf("Error: Failed to load config: %v\n", err)
+		os.Exit(1)
 	}
-	defer sourceFile.Close()
 
-	destFile, err := os.Create(dst)
+	// Create registry client
+	client, err := newRegistryClient(cmd, cfg, srcRegistry)
 	if err != nil {
-		return err
+		cmd.Printf("Error: Failed to create registry client: %v\n", err)
+		os.Exit(1)
 	}
-	defer destFile.Close()
 
-	if _, err := io.Copy(destFile, sourceFile); err != nil {
-		return err
+	c := &imageCopier{
+		cmd:          cmd,
+		src:          client,
+		srcName:      srcName,
+		dst:          client,
+		dstName:      dstName,
+		sameRegistry: true,
+	}
+
+	digest, _, err := c.copyTag(srcTag, dstTag, nil)
+	if err != nil {
+		cmd.Printf("Error: Failed to tag image: %v\n", err)
+		os.Exit(1)
 	}
 
-	return nil
+	cmd.Printf("Tagged %s as %s\n", source, target)
+	cmd.Printf("Digest: %s\n", digest)
 }
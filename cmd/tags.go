@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ioworker0/timage/pkg/config"
+	"github.com/ioworker0/timage/pkg/registry"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tagsFilter  string
+	tagsSemver  string
+	tagsLatest  int
+	tagsSort    string
+	tagsReverse bool
+)
+
+var tagsCmd = &cobra.Command{
+	Use:   "tags [repository]",
+	Short: "List the tags of a remote repository",
+	Long: `List the tags of a repository in a registry, e.g.
+
+  timage tags nginx
+  timage tags harbor.example.com/project/app --semver ">=1.2" --sort semver`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name, _, registryURL := parseImageRef(args[0])
+
+		filter := &registry.TagFilter{
+			Regex:  tagsFilter,
+			Semver: tagsSemver,
+			Latest: tagsLatest,
+		}
+		if err := filter.Compile(); err != nil {
+			cmd.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := registry.SortTags(nil, tagsSort); err != nil {
+			cmd.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Get auth from config
+		configDir, err := config.GetConfigDir()
+		if err != nil {
+			cmd.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		cfg, err := config.NewManager(configDir)
+		if err != nil {
+			cmd.Printf("Error: Failed to load config: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Create registry client
+		client, err := newRegistryClient(cmd, cfg, registryURL)
+		if err != nil {
+			cmd.Printf("Error: Failed to create registry client: %v\n", err)
+			os.Exit(1)
+		}
+
+		tags, err := client.ListTags(name)
+		if err != nil {
+			cmd.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		tags, err = filter.Apply(tags)
+		if err != nil {
+			cmd.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := registry.SortTags(tags, tagsSort); err != nil {
+			cmd.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if tagsReverse {
+			for i, j := 0, len(tags)-1; i < j; i, j = i+1, j-1 {
+				tags[i], tags[j] = tags[j], tags[i]
+			}
+		}
+
+		// One tag per line on stdout so the output can be piped
+		for _, tag := range tags {
+			fmt.Fprintln(cmd.OutOrStdout(), tag)
+		}
+	},
+}
+
+func init() {
+	tagsCmd.Flags().StringVar(&tagsFilter, "filter", "", "Only show tags matching this regular expression")
+	tagsCmd.Flags().StringVar(&tagsSemver, "semver", "", "Only show tags that are semantic versions in this range, e.g. \">=1.2, <2\"")
+	tagsCmd.Flags().IntVar(&tagsLatest, "latest", 0, "Only show the N newest semantic versions")
+	tagsCmd.Flags().StringVar(&tagsSort, "sort", registry.TagSortLexical, "Sort order: lexical or semver")
+	tagsCmd.Flags().BoolVarP(&tagsReverse, "reverse", "r", false, "Reverse the sort order")
+	rootCmd.AddCommand(tagsCmd)
+}
@@ -2,9 +2,15 @@ module github.com/ioworker0/timage
 
 go 1.24.0
 
+require (
+	github.com/Masterminds/semver/v3 v3.5.0
+	github.com/klauspost/compress v1.18.0
+	github.com/spf13/cobra v1.10.2
+	golang.org/x/net v0.48.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/cobra v1.10.2 // indirect
 	github.com/spf13/pflag v1.0.10 // indirect
-	golang.org/x/net v0.48.0 // indirect
 )
@@ -7,16 +7,33 @@ import (
 	"path/filepath"
 )
 
+// DefaultMaxConcurrentDownloads is the number of layers pulled in parallel
+// when neither the flag nor the config file sets a value
+const DefaultMaxConcurrentDownloads = 3
+
 // Config represents the application configuration
 type Config struct {
-	DefaultProxy string                   `json:"default_proxy,omitempty"`
-	Auth         map[string]AuthEntry     `json:"auth,omitempty"`
-	Registries   map[string]RegistryEntry `json:"registries,omitempty"`
+	DefaultProxy           string                   `json:"default_proxy,omitempty"`
+	MaxConcurrentDownloads int                      `json:"max_concurrent_downloads,omitempty"`
+	Retry                  *RetryConfig             `json:"retry,omitempty"`
+	Auth                   map[string]AuthEntry     `json:"auth,omitempty"`
+	Registries             map[string]RegistryEntry `json:"registries,omitempty"`
+}
+
+// RetryConfig represents the retry policy for registry requests
+// Durations use Go duration syntax, e.g. "500ms" or "30s".
+type RetryConfig struct {
+	MaxAttempts    int    `json:"max_attempts,omitempty"`
+	InitialBackoff string `json:"initial_backoff,omitempty"`
+	MaxBackoff     string `json:"max_backoff,omitempty"`
 }
 
 // RegistryEntry represents registry-specific configuration
 type RegistryEntry struct {
 	Proxy string `json:"proxy,omitempty"`
+	// UploadChunkSize enables chunked uploads with chunks of this size (e.g. "64MB");
+	// empty means blobs are uploaded in a single request
+	UploadChunkSize string `json:"upload_chunk_size,omitempty"`
 }
 
 // Manager manages configuration
@@ -105,7 +122,7 @@ func (m *Manager) SetDefaultProxy(proxy string) {
 
 // GetRegistryProxy returns the proxy URL for a specific registry
 func (m *Manager) GetRegistryProxy(registry string) string {
-	if entry, ok := m.config.Registries[registry]; ok {
+	if entry, ok := m.config.Registries[registry]; ok && entry.Proxy != "" {
 		return entry.Proxy
 	}
 	return m.config.DefaultProxy
@@ -116,5 +133,28 @@ func (m *Manager) SetRegistryProxy(registry, proxy string) {
 	if m.config.Registries == nil {
 		m.config.Registries = make(map[string]RegistryEntry)
 	}
-	m.config.Registries[registry] = RegistryEntry{Proxy: proxy}
+	entry := m.config.Registries[registry]
+	entry.Proxy = proxy
+	m.config.Registries[registry] = entry
+}
+
+// GetRegistryUploadChunkSize returns the upload chunk size for a specific registry
+func (m *Manager) GetRegistryUploadChunkSize(registry string) string {
+	return m.config.Registries[registry].UploadChunkSize
+}
+
+// GetMaxConcurrentDownloads returns the number of layers to download in parallel
+func (m *Manager) GetMaxConcurrentDownloads() int {
+	if m.config.MaxConcurrentDownloads > 0 {
+		return m.config.MaxConcurrentDownloads
+	}
+	return DefaultMaxConcurrentDownloads
+}
+
+// GetRetryConfig returns the retry policy settings; zero values mean "use the default"
+func (m *Manager) GetRetryConfig() RetryConfig {
+	if m.config.Retry == nil {
+		return RetryConfig{}
+	}
+	return *m.config.Retry
 }
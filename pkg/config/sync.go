@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SyncFile describes repositories to mirror with timage sync
+type SyncFile struct {
+	// Destination is the default registry prefix images are mirrored to, e.g. harbor.example.com/mirror
+	Destination string `yaml:"destination"`
+	// Platforms are the default platforms to mirror from multi-arch images; empty means all
+	Platforms []string    `yaml:"platforms"`
+	Images    []SyncImage `yaml:"images"`
+}
+
+// SyncImage is a source repository in a sync file
+type SyncImage struct {
+	// Source is the repository to mirror, without a tag, e.g. docker.io/library/nginx
+	Source string `yaml:"source"`
+	// Destination overrides the sync file's destination prefix
+	Destination string `yaml:"destination"`
+	// Repository overrides the repository path below the destination prefix,
+	// which defaults to the source repository path
+	Repository string `yaml:"repository"`
+	// Platforms overrides the sync file's platforms
+	Platforms []string `yaml:"platforms"`
+	Tags      SyncTags `yaml:"tags"`
+}
+
+// SyncTags selects the tags of a source repository to mirror
+type SyncTags struct {
+	Exact  []string `yaml:"exact"`
+	Regex  string   `yaml:"regex"`
+	Semver string   `yaml:"semver"`
+	Latest int      `yaml:"latest"`
+}
+
+// LoadSyncFile reads and validates a sync file
+// Per-image destination and platforms are filled in from the file's defaults.
+func LoadSyncFile(path string) (*SyncFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync file: %w", err)
+	}
+
+	var file SyncFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse sync file: %w", err)
+	}
+
+	if len(file.Images) == 0 {
+		return nil, fmt.Errorf("sync file lists no images")
+	}
+
+	for i := range file.Images {
+		image := &file.Images[i]
+		if image.Source == "" {
+			return nil, fmt.Errorf("image %d: source is required", i+1)
+		}
+		if image.Destination == "" {
+			image.Destination = file.Destination
+		}
+		if image.Destination == "" {
+			return nil, fmt.Errorf("image %s: no destination set", image.Source)
+		}
+		if image.Platforms == nil {
+			image.Platforms = file.Platforms
+		}
+	}
+
+	return &file, nil
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 )
 
 // TokenResponse represents the token response from auth server
@@ -25,9 +26,12 @@ type AuthConfig struct {
 }
 
 // AuthHandler handles authentication with Docker registry
+// It is safe for concurrent use: parallel requests share the token it obtains.
 type AuthHandler struct {
 	client *http.Client
 	auth   *AuthConfig
+	// mu guards auth.Token, which is replaced when a token expires
+	mu sync.RWMutex
 }
 
 // NewAuthHandler creates a new auth handler
@@ -40,9 +44,13 @@ func NewAuthHandler(client *http.Client, auth *AuthConfig) *AuthHandler {
 
 // AddAuth adds authentication headers to the request
 func (a *AuthHandler) AddAuth(req *http.Request) {
+	a.mu.RLock()
+	token := a.auth.Token
+	a.mu.RUnlock()
+
 	// If we have a Bearer token, use it
-	if a.auth.Token != "" {
-		req.Header.Set("Authorization", "Bearer "+a.auth.Token)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
 		return
 	}
 
@@ -101,7 +109,9 @@ func (a *AuthHandler) handleBearerAuth(req *http.Request, authHeader string) err
 	}
 
 	// Save the token for future requests
+	a.mu.Lock()
 	a.auth.Token = token
+	a.mu.Unlock()
 
 	return nil
 }
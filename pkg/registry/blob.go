@@ -1,26 +1,54 @@
 package registry
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // DownloadBlob downloads a blob (layer or config) from the registry
 func (c *Client) DownloadBlob(name, digest string, destPath string) error {
-	path := fmt.Sprintf("/%s/blobs/%s", name, digest)
+	return c.DownloadBlobWithProgress(name, digest, destPath, nil)
+}
 
-	resp, err := c.doRequest("GET", path, nil)
-	if err != nil {
-		return fmt.Errorf("failed to get blob: %w", err)
+// DownloadBlobWithProgress downloads a blob with progress reporting
+// The content is verified against the digest while streaming; on mismatch the
+// destination file is removed and an *ErrDigestMismatch is returned.
+func (c *Client) DownloadBlobWithProgress(name, digest, destPath string, progress func(int64, int64)) error {
+	return c.downloadBlob(name, digest, destPath, false, progress)
+}
+
+// ResumeBlobDownload downloads a blob, continuing from whatever is already in destPath
+// The existing bytes are requested to be skipped with an HTTP Range request; if the
+// registry ignores the range the download starts over. When the transfer fails the
+// partial file is kept so a later call can resume it. The complete file is verified
+// against the digest and discarded on mismatch. A partial file that is corrupt and at
+// least as long as the blob is discarded and the download started over. destPath is
+// discarded by truncating it, never by removing it, so a lock held on it stays valid.
+// A connection dropped mid-transfer is resumed according to the client's retry policy.
+func (c *Client) ResumeBlobDownload(name, digest, destPath string, progress func(int64, int64)) error {
+	for attempt := 1; ; attempt++ {
+		err := c.downloadBlob(name, digest, destPath, true, progress)
+
+		// Requests are already retried by send; only retry transfers cut off mid-stream here
+		var interrupted *transferError
+		if !errors.As(err, &interrupted) || !isRetryableError(err) || attempt >= c.retry.MaxAttempts {
+			return err
+		}
+		time.Sleep(c.retry.backoff(attempt, nil))
 	}
-	defer resp.Body.Close()
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+// downloadBlob implements DownloadBlobWithProgress and ResumeBlobDownload
+func (c *Client) downloadBlob(name, digest, destPath string, resume bool, progress func(int64, int64)) error {
+	verifier, err := newDigestVerifier(digest)
+	if err != nil {
+		return err
 	}
 
 	// Create destination directory if it doesn't exist
@@ -28,64 +56,149 @@ func (c *Client) DownloadBlob(name, digest string, destPath string) error {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	// Create destination file
-	file, err := os.Create(destPath)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+	// Hash what we already have so the final digest covers the whole blob
+	var offset int64
+	if resume {
+		offset, err = hashPartial(destPath, verifier)
+		if err != nil {
+			return err
+		}
 	}
-	defer file.Close()
 
-	// Copy blob data to file
-	if _, err := io.Copy(file, resp.Body); err != nil {
-		return fmt.Errorf("failed to write blob: %w", err)
-	}
-
-	return nil
-}
-
-// DownloadBlobWithProgress downloads a blob with progress reporting
-func (c *Client) DownloadBlobWithProgress(name, digest, destPath string, progress func(int64, int64)) error {
 	path := fmt.Sprintf("/%s/blobs/%s", name, digest)
+	var headers map[string]string
+	if offset > 0 {
+		headers = map[string]string{"Range": fmt.Sprintf("bytes=%d-", offset)}
+	}
 
-	resp, err := c.doRequest("GET", path, nil)
+	resp, err := c.doRequest("GET", path, headers)
 	if err != nil {
 		return fmt.Errorf("failed to get blob: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	// Open the destination: append to the partial file if the registry honored the range,
+	// otherwise start over
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0 && contentRangeStart(resp) == offset:
+		flags = os.O_WRONLY | os.O_APPEND
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The partial file is at least as long as the blob; it is complete or corrupt
+		if err := verifier.Verify(); err != nil {
+			// Start over from byte 0; with an empty partial file no range is requested again
+			resp.Body.Close()
+			if err := os.Truncate(destPath, 0); err != nil {
+				return fmt.Errorf("failed to discard corrupt partial download: %w", err)
+			}
+			return c.downloadBlob(name, digest, destPath, resume, progress)
+		}
+		return nil
+	case resp.StatusCode == http.StatusPartialContent:
+		// A range we didn't ask for; drop the partial file so the next attempt starts clean
+		discardDownload(destPath, resume)
+		return fmt.Errorf("registry returned an unexpected range: %s", resp.Header.Get("Content-Range"))
+	case resp.StatusCode == http.StatusOK:
+		if offset > 0 {
+			verifier.reset()
+			offset = 0
+		}
+	default:
 		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
 	}
 
 	// Get content length
 	size := resp.ContentLength
-
-	// Create destination directory if it doesn't exist
-	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+	if size >= 0 {
+		size += offset
 	}
 
-	// Create destination file
-	file, err := os.Create(destPath)
+	file, err := os.OpenFile(destPath, flags, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to create file: %w", err)
 	}
-	defer file.Close()
 
-	// Copy with progress
+	// Copy with progress, hashing as we go
 	writer := &progressWriter{
-		writer:   file,
+		writer:   io.MultiWriter(file, verifier),
 		total:    size,
+		written:  offset,
 		progress: progress,
 	}
 
-	if _, err := io.Copy(writer, resp.Body); err != nil {
-		return fmt.Errorf("failed to write blob: %w", err)
+	_, err = io.Copy(writer, resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// Keep what we received when resuming, it is verified once complete
+		if !resume {
+			os.Remove(destPath)
+		}
+		return fmt.Errorf("failed to write blob: %w", &transferError{err: err})
+	}
+
+	// Never keep content that doesn't match what was requested
+	if err := verifier.Verify(); err != nil {
+		discardDownload(destPath, resume)
+		return err
 	}
 
 	return nil
 }
 
+// discardDownload drops the content of a failed download
+// A partial download being resumed is truncated instead of removed.
+func discardDownload(path string, resume bool) {
+	if resume {
+		os.Truncate(path, 0)
+		return
+	}
+	os.Remove(path)
+}
+
+// transferError marks a failure while streaming a response body
+type transferError struct {
+	err error
+}
+
+func (e *transferError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transferError) Unwrap() error {
+	return e.err
+}
+
+// hashPartial feeds an existing partial download into the verifier and returns its size
+func hashPartial(path string, verifier *digestVerifier) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to open partial download: %w", err)
+	}
+	defer file.Close()
+
+	n, err := io.Copy(verifier, file)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read partial download: %w", err)
+	}
+
+	return n, nil
+}
+
+// contentRangeStart returns the first byte position of a Content-Range header, or -1
+func contentRangeStart(resp *http.Response) int64 {
+	// Format: bytes <start>-<end>/<size>
+	var start, end int64
+	if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-%d", &start, &end); err != nil {
+		return -1
+	}
+	return start
+}
+
 // progressWriter wraps an io.Writer to report progress
 type progressWriter struct {
 	writer   io.Writer
@@ -105,6 +218,45 @@ func (pw *progressWriter) Write(p []byte) (int, error) {
 	return n, err
 }
 
+// GetBlob opens a stream of a blob's content
+// The caller must close the returned reader. The size is -1 if the registry didn't send one.
+func (c *Client) GetBlob(name, digest string) (io.ReadCloser, int64, error) {
+	path := fmt.Sprintf("/%s/blobs/%s", name, digest)
+
+	resp, err := c.doRequest("GET", path, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get blob: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return resp.Body, resp.ContentLength, nil
+}
+
+// GetBlobContent fetches a small blob, such as an image config, into memory
+// The content is verified against the digest.
+func (c *Client) GetBlobContent(name, digest string) ([]byte, error) {
+	body, _, err := c.GetBlob(name, digest)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob: %w", err)
+	}
+
+	if err := VerifyDigest(digest, data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
 // CheckBlob checks if a blob exists in the registry
 func (c *Client) CheckBlob(name, digest string) (bool, error) {
 	path := fmt.Sprintf("/%s/blobs/%s", name, digest)
@@ -143,7 +295,8 @@ func (c *Client) GetBlobSize(name, digest string) (int64, error) {
 	return resp.ContentLength, nil
 }
 
-// UploadBlob uploads a single blob (monolithic upload)
+// UploadBlob uploads a single blob
+// The blob is sent in one request unless a chunk size is set with SetUploadChunkSize.
 func (c *Client) UploadBlob(name, digest, srcPath string) error {
 	// Open the source file
 	file, err := os.Open(srcPath)
@@ -165,7 +318,12 @@ func (c *Client) UploadBlob(name, digest, srcPath string) error {
 	}
 
 	// Upload the blob
-	if err := c.UploadBlobMonolithic(uploadURL, file, fileInfo.Size(), digest); err != nil {
+	if c.chunkSize > 0 {
+		err = c.UploadBlobChunked(uploadURL, file, fileInfo.Size(), digest, c.chunkSize)
+	} else {
+		err = c.UploadBlobMonolithic(uploadURL, file, fileInfo.Size(), digest)
+	}
+	if err != nil {
 		return fmt.Errorf("failed to upload blob: %w", err)
 	}
 
@@ -193,41 +351,29 @@ func (c *Client) StartBlobUpload(name string) (string, error) {
 	}
 
 	// Handle relative URLs
-	if !strings.HasPrefix(uploadURL, "http://") && !strings.HasPrefix(uploadURL, "https://") {
-		uploadURL = c.baseURL + "/v2" + uploadURL
-	}
-
-	return uploadURL, nil
+	return c.resolveLocation(uploadURL)
 }
 
 // UploadBlobMonolithic uploads a blob in a single request
+// If reader implements io.Seeker the upload is replayed from the start when it has to be retried.
 func (c *Client) UploadBlobMonolithic(uploadURL string, reader io.Reader, size int64, digest string) error {
-	// Create request
-	req, err := http.NewRequest("PUT", uploadURL, reader)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Set headers
-	req.Header.Set("Content-Type", "application/octet-stream")
-	req.Header.Set("Content-Length", fmt.Sprintf("%d", size))
-	req.ContentLength = size
-
 	// Add digest query parameter (preserving existing query params)
-	if req.URL.RawQuery != "" {
-		req.URL.RawQuery += "&digest=" + digest
+	if strings.Contains(uploadURL, "?") {
+		uploadURL += "&digest=" + digest
 	} else {
-		req.URL.RawQuery = "digest=" + digest
+		uploadURL += "?digest=" + digest
 	}
 
-	// Add auth
-	c.auth.AddAuth(req)
-	req.Header.Set("Docker-Distribution-API-Version", "registry/2.0")
+	// Set headers
+	headers := map[string]string{
+		"Content-Type":   "application/octet-stream",
+		"Content-Length": fmt.Sprintf("%d", size),
+	}
 
 	// Send request
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.send("PUT", uploadURL, headers, reader, size)
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		return err
 	}
 	defer resp.Body.Close()
 
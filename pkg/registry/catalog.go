@@ -0,0 +1,60 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// catalogPage is the response of the _catalog endpoint
+type catalogPage struct {
+	Repositories []string `json:"repositories"`
+}
+
+// Catalog returns the repositories of the registry, following Link-header pagination
+// With a prefix only repositories starting with it are returned. The distribution
+// API has no prefix filter, but the listing is sorted and can start after a given
+// name, so repositories before the prefix are skipped on registries that support
+// that; the rest is filtered here.
+func (c *Client) Catalog(prefix string) ([]string, error) {
+	path := "/_catalog"
+	if last := catalogStart(prefix); last != "" {
+		path += "?last=" + url.QueryEscape(last)
+	}
+
+	var repositories []string
+	err := c.getPages(path, func(body []byte) error {
+		var page catalogPage
+		if err := json.Unmarshal(body, &page); err != nil {
+			return fmt.Errorf("failed to decode catalog: %w", err)
+		}
+		for _, repository := range page.Repositories {
+			if strings.HasPrefix(repository, prefix) {
+				repositories = append(repositories, repository)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories: %w", err)
+	}
+
+	return repositories, nil
+}
+
+// catalogStart returns a name sorting just before every name starting with prefix,
+// for the last parameter of the catalog, or "" if there is none
+func catalogStart(prefix string) string {
+	if prefix == "" {
+		return ""
+	}
+
+	last := []byte(prefix)
+	i := len(last) - 1
+	if last[i] == 0 {
+		return ""
+	}
+	last[i]--
+	return string(last)
+}
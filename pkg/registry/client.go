@@ -2,9 +2,11 @@ package registry
 
 import (
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/ioworker0/timage/pkg/proxy"
 )
@@ -14,6 +16,8 @@ type Client struct {
 	httpClient *http.Client
 	auth       *AuthHandler
 	baseURL    string
+	retry      RetryPolicy
+	chunkSize  int64
 }
 
 // NewClient creates a new registry client
@@ -45,6 +49,7 @@ func NewClient(registryURL string, authConfig *AuthConfig, proxyURL string) (*Cl
 		httpClient: httpClient,
 		auth:       auth,
 		baseURL:    parsedURL.String(),
+		retry:      DefaultRetryPolicy(),
 	}, nil
 }
 
@@ -58,25 +63,43 @@ func createHTTPClient(proxyURL string) (*http.Client, error) {
 
 // doRequest performs an HTTP request with authentication
 func (c *Client) doRequest(method, path string, headers map[string]string) (*http.Response, error) {
-	// Build full URL
-	fullURL := c.baseURL + "/v2" + path
+	return c.send(method, c.baseURL+"/v2"+path, headers, nil, 0)
+}
 
-	// Create request
-	req, err := http.NewRequest(method, fullURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+// send performs an HTTP request against a full URL with authentication, retrying
+// transient failures according to the client's retry policy
+// A body that implements io.Seeker is rewound before every attempt; any other
+// body can only be sent once, so such requests are never retried.
+func (c *Client) send(method, fullURL string, headers map[string]string, body io.Reader, size int64) (*http.Response, error) {
+	maxAttempts := c.retry.MaxAttempts
+	if _, ok := body.(io.Seeker); body != nil && !ok {
+		maxAttempts = 1
 	}
 
-	// Add custom headers
-	for k, v := range headers {
-		req.Header.Set(k, v)
-	}
+	for attempt := 1; ; attempt++ {
+		resp, err := c.sendWithAuth(method, fullURL, headers, body, size)
 
-	// Add authentication
-	c.auth.AddAuth(req)
+		retryable := isRetryableError(err) || (err == nil && isRetryableStatus(resp.StatusCode))
+		if !retryable || attempt >= maxAttempts {
+			return resp, err
+		}
 
-	// Set default headers
-	req.Header.Set("Docker-Distribution-API-Version", "registry/2.0")
+		wait := c.retry.backoff(attempt, resp)
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		time.Sleep(wait)
+	}
+}
+
+// sendWithAuth performs a single HTTP request, answering an authentication challenge once
+func (c *Client) sendWithAuth(method, fullURL string, headers map[string]string, body io.Reader, size int64) (*http.Response, error) {
+	// Create request
+	req, err := c.newRequest(method, fullURL, headers, body, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
 
 	// Send request
 	resp, err := c.httpClient.Do(req)
@@ -92,19 +115,17 @@ func (c *Client) doRequest(method, path string, headers map[string]string) (*htt
 		}
 		resp.Body.Close()
 
+		// A body that was already sent can't be replayed
+		if _, ok := body.(io.Seeker); body != nil && !ok {
+			return nil, fmt.Errorf("authentication required and request body can't be resent")
+		}
+
 		// Retry with new auth
-		req, err = http.NewRequest(method, fullURL, nil)
+		req, err = c.newRequest(method, fullURL, headers, body, size)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create retry request: %w", err)
 		}
 
-		for k, v := range headers {
-			req.Header.Set(k, v)
-		}
-
-		c.auth.AddAuth(req)
-		req.Header.Set("Docker-Distribution-API-Version", "registry/2.0")
-
 		resp, err = c.httpClient.Do(req)
 		if err != nil {
 			return nil, fmt.Errorf("retry request failed: %w", err)
@@ -114,6 +135,54 @@ func (c *Client) doRequest(method, path string, headers map[string]string) (*htt
 	return resp, nil
 }
 
+// newRequest builds a request with custom, auth and default headers,
+// rewinding a seekable body to its start
+func (c *Client) newRequest(method, fullURL string, headers map[string]string, body io.Reader, size int64) (*http.Request, error) {
+	if seeker, ok := body.(io.Seeker); ok {
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequest(method, fullURL, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+		// Let the transport see EOF as end of body rather than closing our file
+		req.Body = io.NopCloser(body)
+	}
+
+	// Add custom headers
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	// Add authentication
+	c.auth.AddAuth(req)
+
+	// Set default headers
+	req.Header.Set("Docker-Distribution-API-Version", "registry/2.0")
+
+	return req, nil
+}
+
+// resolveLocation resolves a Location header, which may be relative to the registry host
+func (c *Client) resolveLocation(location string) (string, error) {
+	base, err := url.Parse(c.baseURL)
+	if err != nil {
+		return "", err
+	}
+
+	ref, err := url.Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("invalid location %q: %w", location, err)
+	}
+
+	return base.ResolveReference(ref).String(), nil
+}
+
 // Ping checks if the registry is accessible
 func (c *Client) Ping() error {
 	resp, err := c.doRequest("GET", "/", nil)
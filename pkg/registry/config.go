@@ -0,0 +1,137 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RootFSTypeLayers is the only rootfs type defined by the image spec
+const RootFSTypeLayers = "layers"
+
+// ImageConfig represents an image configuration blob
+// Docker and OCI configs share this structure; the fields Docker adds on top of
+// the OCI image spec are included where they describe the image.
+type ImageConfig struct {
+	Created      *time.Time `json:"created,omitempty"`
+	Author       string     `json:"author,omitempty"`
+	Architecture string     `json:"architecture"`
+	OS           string     `json:"os"`
+	OSVersion    string     `json:"os.version,omitempty"`
+	OSFeatures   []string   `json:"os.features,omitempty"`
+	Variant      string     `json:"variant,omitempty"`
+
+	Config  ContainerConfig `json:"config"`
+	RootFS  RootFS          `json:"rootfs"`
+	History []History       `json:"history,omitempty"`
+
+	// Docker only
+	DockerVersion string `json:"docker_version,omitempty"`
+}
+
+// ContainerConfig holds the defaults for containers run from an image
+type ContainerConfig struct {
+	User         string              `json:"User,omitempty"`
+	ExposedPorts map[string]struct{} `json:"ExposedPorts,omitempty"`
+	Env          []string            `json:"Env,omitempty"`
+	Entrypoint   []string            `json:"Entrypoint,omitempty"`
+	Cmd          []string            `json:"Cmd,omitempty"`
+	Volumes      map[string]struct{} `json:"Volumes,omitempty"`
+	WorkingDir   string              `json:"WorkingDir,omitempty"`
+	Labels       map[string]string   `json:"Labels,omitempty"`
+	StopSignal   string              `json:"StopSignal,omitempty"`
+	ArgsEscaped  bool                `json:"ArgsEscaped,omitempty"`
+
+	// Docker only
+	Healthcheck *HealthConfig `json:"Healthcheck,omitempty"`
+	OnBuild     []string      `json:"OnBuild,omitempty"`
+	Shell       []string      `json:"Shell,omitempty"`
+}
+
+// HealthConfig is the HEALTHCHECK of a Docker image
+type HealthConfig struct {
+	Test          []string      `json:"Test,omitempty"`
+	Interval      time.Duration `json:"Interval,omitempty"`
+	Timeout       time.Duration `json:"Timeout,omitempty"`
+	StartPeriod   time.Duration `json:"StartPeriod,omitempty"`
+	StartInterval time.Duration `json:"StartInterval,omitempty"`
+	Retries       int           `json:"Retries,omitempty"`
+}
+
+// RootFS lists the uncompressed digests of an image's layers
+type RootFS struct {
+	Type    string   `json:"type"`
+	DiffIDs []string `json:"diff_ids"`
+}
+
+// History describes how a layer, or an instruction without a layer, was created
+type History struct {
+	Created    *time.Time `json:"created,omitempty"`
+	CreatedBy  string     `json:"created_by,omitempty"`
+	Author     string     `json:"author,omitempty"`
+	Comment    string     `json:"comment,omitempty"`
+	EmptyLayer bool       `json:"empty_layer,omitempty"`
+}
+
+// ParseImageConfig parses an image configuration blob
+func ParseImageConfig(data []byte) (*ImageConfig, error) {
+	var config ImageConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to decode image config: %w", err)
+	}
+
+	return &config, nil
+}
+
+// Platform returns the platform the image was built for
+func (c *ImageConfig) Platform() Platform {
+	return Platform{
+		Architecture: c.Architecture,
+		OS:           c.OS,
+		OSVersion:    c.OSVersion,
+		OSFeatures:   c.OSFeatures,
+		Variant:      c.Variant,
+	}
+}
+
+// ValidateDiffIDs checks that rootfs.diff_ids describes the layers of a manifest
+// There must be one well-formed diff ID per layer, in the same order. Whether each
+// diff ID matches its layer's content can only be checked by decompressing the layer,
+// which storage.VerifyLayerDiffID does.
+func (c *ImageConfig) ValidateDiffIDs(layers []Descriptor) error {
+	if c.RootFS.Type != RootFSTypeLayers {
+		return fmt.Errorf("unsupported rootfs type %q", c.RootFS.Type)
+	}
+
+	if len(c.RootFS.DiffIDs) != len(layers) {
+		return fmt.Errorf("config lists %d layers but the manifest has %d", len(c.RootFS.DiffIDs), len(layers))
+	}
+
+	for i, diffID := range c.RootFS.DiffIDs {
+		if err := ValidateDigest(diffID); err != nil {
+			return fmt.Errorf("layer %d: invalid diff id: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// ValidateHistory checks that the history has one non-empty entry per layer
+// History is optional, so a config without any is valid.
+func (c *ImageConfig) ValidateHistory(layers []Descriptor) error {
+	if len(c.History) == 0 {
+		return nil
+	}
+
+	var count int
+	for _, entry := range c.History {
+		if !entry.EmptyLayer {
+			count++
+		}
+	}
+	if count != len(layers) {
+		return fmt.Errorf("config history lists %d layers but the manifest has %d", count, len(layers))
+	}
+
+	return nil
+}
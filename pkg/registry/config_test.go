@@ -0,0 +1,72 @@
+package registry
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImageConfigValidateDiffIDs(t *testing.T) {
+	diffID := "sha256:" + strings.Repeat("ab", 32)
+	layers := []Descriptor{{Digest: "sha256:" + strings.Repeat("01", 32)}, {Digest: "sha256:" + strings.Repeat("02", 32)}}
+
+	tests := []struct {
+		name    string
+		rootfs  RootFS
+		layers  []Descriptor
+		wantErr string
+	}{
+		{
+			name:   "one diff id per layer",
+			rootfs: RootFS{Type: RootFSTypeLayers, DiffIDs: []string{diffID, diffID}},
+			layers: layers,
+		},
+		{
+			name:   "no layers",
+			rootfs: RootFS{Type: RootFSTypeLayers},
+		},
+		{
+			name:    "unknown rootfs type",
+			rootfs:  RootFS{Type: "snapshot", DiffIDs: []string{diffID, diffID}},
+			layers:  layers,
+			wantErr: `unsupported rootfs type "snapshot"`,
+		},
+		{
+			name:    "fewer diff ids than layers",
+			rootfs:  RootFS{Type: RootFSTypeLayers, DiffIDs: []string{diffID}},
+			layers:  layers,
+			wantErr: "config lists 1 layers but the manifest has 2",
+		},
+		{
+			name:    "more diff ids than layers",
+			rootfs:  RootFS{Type: RootFSTypeLayers, DiffIDs: []string{diffID, diffID, diffID}},
+			layers:  layers,
+			wantErr: "config lists 3 layers but the manifest has 2",
+		},
+		{
+			name:    "truncated diff id",
+			rootfs:  RootFS{Type: RootFSTypeLayers, DiffIDs: []string{diffID, "sha256:abcd"}},
+			layers:  layers,
+			wantErr: "layer 2: invalid diff id",
+		},
+		{
+			name:    "unsupported algorithm",
+			rootfs:  RootFS{Type: RootFSTypeLayers, DiffIDs: []string{"md5:" + strings.Repeat("ab", 16), diffID}},
+			layers:  layers,
+			wantErr: "layer 1: invalid diff id",
+		},
+	}
+
+	for _, tt := range tests {
+		config := &ImageConfig{RootFS: tt.rootfs}
+		err := config.ValidateDiffIDs(tt.layers)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: ValidateDiffIDs failed: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: ValidateDiffIDs error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package registry
+
+import "fmt"
+
+// Image formats a manifest can be converted to
+const (
+	FormatDocker = "docker"
+	FormatOCI    = "oci"
+)
+
+// Layer compressions
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// LayerInfo describes how a layer media type stores its content
+type LayerInfo struct {
+	Compression      string
+	NonDistributable bool
+}
+
+// layerInfos maps every layer media type we can convert
+var layerInfos = map[string]LayerInfo{
+	MediaTypeDockerLayer:             {Compression: CompressionGzip},
+	MediaTypeDockerUncompressedLayer: {Compression: CompressionNone},
+	MediaTypeDockerForeignLayer:      {Compression: CompressionGzip, NonDistributable: true},
+
+	MediaTypeOCILayer:     {Compression: CompressionNone},
+	MediaTypeOCILayerGzip: {Compression: CompressionGzip},
+	MediaTypeOCILayerZstd: {Compression: CompressionZstd},
+
+	MediaTypeOCINonDistributableLayer:     {Compression: CompressionNone, NonDistributable: true},
+	MediaTypeOCINonDistributableLayerGzip: {Compression: CompressionGzip, NonDistributable: true},
+	MediaTypeOCINonDistributableLayerZstd: {Compression: CompressionZstd, NonDistributable: true},
+}
+
+// ParseFormat validates an image format name
+func ParseFormat(format string) (string, error) {
+	switch format {
+	case FormatDocker, FormatOCI:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown image format %q (expected %s or %s)", format, FormatDocker, FormatOCI)
+}
+
+// GetLayerInfo returns the compression of a layer media type
+func GetLayerInfo(mediaType string) (LayerInfo, error) {
+	info, ok := layerInfos[mediaType]
+	if !ok {
+		return LayerInfo{}, fmt.Errorf("unsupported layer media type %q", mediaType)
+	}
+	return info, nil
+}
+
+// LayerMediaType returns the layer media type for a format and compression
+// Docker has no zstd layer type, so ok is false and the layer has to be recompressed.
+func LayerMediaType(format string, info LayerInfo) (mediaType string, ok bool) {
+	if format == FormatOCI {
+		switch {
+		case info.NonDistributable && info.Compression == CompressionNone:
+			return MediaTypeOCINonDistributableLayer, true
+		case info.NonDistributable && info.Compression == CompressionGzip:
+			return MediaTypeOCINonDistributableLayerGzip, true
+		case info.NonDistributable && info.Compression == CompressionZstd:
+			return MediaTypeOCINonDistributableLayerZstd, true
+		case info.Compression == CompressionNone:
+			return MediaTypeOCILayer, true
+		case info.Compression == CompressionGzip:
+			return MediaTypeOCILayerGzip, true
+		case info.Compression == CompressionZstd:
+			return MediaTypeOCILayerZstd, true
+		}
+		return "", false
+	}
+
+	switch {
+	case info.NonDistributable && info.Compression == CompressionGzip:
+		return MediaTypeDockerForeignLayer, true
+	case info.NonDistributable:
+		// Foreign layers are always gzip
+		return "", false
+	case info.Compression == CompressionNone:
+		return MediaTypeDockerUncompressedLayer, true
+	case info.Compression == CompressionGzip:
+		return MediaTypeDockerLayer, true
+	}
+	return "", false
+}
+
+// ManifestMediaType returns the image manifest media type of a format
+func ManifestMediaType(format string) string {
+	if format == FormatOCI {
+		return MediaTypeOCIManifest
+	}
+	return MediaTypeDockerManifest
+}
+
+// IndexMediaType returns the manifest list or index media type of a format
+func IndexMediaType(format string) string {
+	if format == FormatOCI {
+		return MediaTypeOCIIndex
+	}
+	return MediaTypeDockerManifestList
+}
+
+// ConfigMediaType returns the image config media type of a format
+func ConfigMediaType(format string) string {
+	if format == FormatOCI {
+		return MediaTypeOCIConfig
+	}
+	return MediaTypeDockerConfig
+}
@@ -0,0 +1,96 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// CopyBlob streams a blob from one registry to another without storing it locally
+// The content is verified against the digest while streaming, and the destination
+// checks it again when the upload completes. The blob is sent in chunks when the
+// destination client has a chunk size set. A transfer cut off midway is started
+// over according to the destination client's retry policy.
+func CopyBlob(src *Client, srcName string, dst *Client, dstName, digest string, size int64, progress func(int64, int64)) error {
+	for attempt := 1; ; attempt++ {
+		err := copyBlob(src, srcName, dst, dstName, digest, size, progress)
+
+		var interrupted *transferError
+		if !errors.As(err, &interrupted) || !isRetryableError(err) || attempt >= dst.retry.MaxAttempts {
+			return err
+		}
+		time.Sleep(dst.retry.backoff(attempt, nil))
+	}
+}
+
+// copyBlob implements one attempt of CopyBlob
+func copyBlob(src *Client, srcName string, dst *Client, dstName, digest string, size int64, progress func(int64, int64)) error {
+	verifier, err := newDigestVerifier(digest)
+	if err != nil {
+		return err
+	}
+
+	body, length, err := src.GetBlob(srcName, digest)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if size <= 0 {
+		size = length
+	}
+
+	uploadURL, err := dst.StartBlobUpload(dstName)
+	if err != nil {
+		return fmt.Errorf("failed to start upload: %w", err)
+	}
+
+	reader := &verifyingReader{
+		reader:   body,
+		verifier: verifier,
+		progress: &progressWriter{writer: io.Discard, total: size, progress: progress},
+	}
+
+	// Registries behind proxies with a body size limit need the destination's chunk size
+	if dst.chunkSize > 0 {
+		err = dst.uploadStreamChunked(uploadURL, reader, digest, dst.chunkSize)
+	} else {
+		err = dst.UploadBlobMonolithic(uploadURL, reader, size, digest)
+	}
+	if err != nil {
+		if reader.err != nil {
+			// The upload failed because reading the source did
+			return fmt.Errorf("failed to read blob: %w", &transferError{err: reader.err})
+		}
+		return fmt.Errorf("failed to upload blob: %w", err)
+	}
+
+	return nil
+}
+
+// verifyingReader hashes everything read through it and fails at EOF on a digest mismatch,
+// so corrupt content never completes an upload
+type verifyingReader struct {
+	reader   io.Reader
+	verifier *digestVerifier
+	progress *progressWriter
+	err      error
+}
+
+func (vr *verifyingReader) Read(p []byte) (int, error) {
+	n, err := vr.reader.Read(p)
+	vr.verifier.Write(p[:n])
+	vr.progress.Write(p[:n])
+
+	if err == io.EOF {
+		if verifyErr := vr.verifier.Verify(); verifyErr != nil {
+			vr.err = verifyErr
+			return n, verifyErr
+		}
+	} else if err != nil {
+		vr.err = err
+	}
+
+	return n, err
+}
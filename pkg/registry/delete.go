@@ -0,0 +1,80 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrDeleteDisabled is returned when a registry refuses deletes with 405 Method Not Allowed
+// Registries typically do this when deletion is turned off in their configuration.
+var ErrDeleteDisabled = errors.New("the registry does not allow deleting manifests (405 Method Not Allowed); deletion may be disabled in its configuration")
+
+// ErrDeleteForbidden is returned when the credentials don't allow deleting manifests
+var ErrDeleteForbidden = errors.New("permission denied; check that your account is allowed to delete in this repository")
+
+// DeleteManifest deletes a manifest by digest
+// Every tag referring to the manifest goes with it. Registries only accept digests
+// here; resolve a tag with GetManifestDigest first.
+func (c *Client) DeleteManifest(name, digest string) error {
+	if !isDigest(digest) {
+		return fmt.Errorf("manifests can only be deleted by digest, not by tag %q", digest)
+	}
+
+	path := fmt.Sprintf("/%s/manifests/%s", name, digest)
+
+	resp, err := c.doRequest("DELETE", path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete manifest: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusAccepted, http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusMethodNotAllowed:
+		return ErrDeleteDisabled
+	}
+
+	return deleteStatusError(resp, "manifest "+digest, name)
+}
+
+// ResolveDeleteDigest resolves a tag to the manifest digest DeleteManifest needs
+// Missing permission and a missing tag are reported like DeleteManifest reports them.
+func (c *Client) ResolveDeleteDigest(name, tag string) (string, error) {
+	path := fmt.Sprintf("/%s/manifests/%s", name, tag)
+	headers := map[string]string{
+		"Accept": manifestAccept,
+	}
+
+	resp, err := c.doRequest("HEAD", path, headers)
+	if err != nil {
+		return "", fmt.Errorf("failed to head manifest: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", deleteStatusError(resp, "tag "+tag, name)
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("no digest found in response")
+	}
+
+	return digest, nil
+}
+
+// deleteStatusError turns a failed response for what in repository name into an error
+func deleteStatusError(resp *http.Response, what, name string) error {
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrDeleteForbidden
+	case http.StatusNotFound:
+		return fmt.Errorf("%s not found in %s", what, name)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+}
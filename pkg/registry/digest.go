@@ -0,0 +1,152 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+)
+
+// ErrDigestMismatch is returned when downloaded content does not match the expected digest
+type ErrDigestMismatch struct {
+	Expected string
+	Actual   string
+}
+
+func (e *ErrDigestMismatch) Error() string {
+	return fmt.Sprintf("digest mismatch: expected %s, got %s", e.Expected, e.Actual)
+}
+
+// digestVerifier computes a digest over the data written to it
+type digestVerifier struct {
+	algorithm string
+	expected  string
+	hash      hash.Hash
+}
+
+// newDigestVerifier creates a verifier for the given digest
+func newDigestVerifier(digest string) (*digestVerifier, error) {
+	algorithm, _, ok := strings.Cut(digest, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid digest: %q", digest)
+	}
+
+	var h hash.Hash
+	switch algorithm {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return nil, fmt.Errorf("unsupported digest algorithm: %s", algorithm)
+	}
+
+	return &digestVerifier{
+		algorithm: algorithm,
+		expected:  digest,
+		hash:      h,
+	}, nil
+}
+
+func (v *digestVerifier) Write(p []byte) (int, error) {
+	return v.hash.Write(p)
+}
+
+// reset discards the data written so far
+func (v *digestVerifier) reset() {
+	v.hash.Reset()
+}
+
+// Digest returns the digest of the data written so far
+func (v *digestVerifier) Digest() string {
+	return v.algorithm + ":" + hex.EncodeToString(v.hash.Sum(nil))
+}
+
+// Verify checks the data written so far against the expected digest
+func (v *digestVerifier) Verify() error {
+	if actual := v.Digest(); actual != v.expected {
+		return &ErrDigestMismatch{Expected: v.expected, Actual: actual}
+	}
+	return nil
+}
+
+// VerifyDigest checks data against the expected digest
+func VerifyDigest(digest string, data []byte) error {
+	verifier, err := newDigestVerifier(digest)
+	if err != nil {
+		return err
+	}
+
+	verifier.Write(data)
+	return verifier.Verify()
+}
+
+// VerifyReader checks everything read from r against the expected digest
+func VerifyReader(digest string, r io.Reader) error {
+	verifier, err := newDigestVerifier(digest)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(verifier, r); err != nil {
+		return err
+	}
+	return verifier.Verify()
+}
+
+// VerifyingReader checks everything read through it against a digest
+// Reaching the end of content that doesn't match fails with an *ErrDigestMismatch
+// instead of io.EOF.
+type VerifyingReader struct {
+	reader   io.Reader
+	verifier *digestVerifier
+}
+
+// NewVerifyingReader wraps r to verify what is read from it against digest
+func NewVerifyingReader(digest string, r io.Reader) (*VerifyingReader, error) {
+	verifier, err := newDigestVerifier(digest)
+	if err != nil {
+		return nil, err
+	}
+	return &VerifyingReader{reader: r, verifier: verifier}, nil
+}
+
+func (vr *VerifyingReader) Read(p []byte) (int, error) {
+	n, err := vr.reader.Read(p)
+	vr.verifier.Write(p[:n])
+	if err == io.EOF {
+		if verifyErr := vr.verifier.Verify(); verifyErr != nil {
+			return n, verifyErr
+		}
+	}
+	return n, err
+}
+
+// ComputeDigest returns the sha256 digest of data
+func ComputeDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// ValidateDigest checks that a digest is well formed and uses an algorithm we support
+func ValidateDigest(digest string) error {
+	verifier, err := newDigestVerifier(digest)
+	if err != nil {
+		return err
+	}
+
+	_, encoded, _ := strings.Cut(digest, ":")
+	if raw, err := hex.DecodeString(encoded); err != nil || len(raw) != verifier.hash.Size() {
+		return fmt.Errorf("invalid digest: %q", digest)
+	}
+
+	return nil
+}
+
+// isDigest reports whether a manifest reference is a digest rather than a tag
+func isDigest(reference string) bool {
+	return strings.Contains(reference, ":")
+}
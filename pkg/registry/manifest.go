@@ -6,9 +6,12 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 )
 
-// Manifest represents a Docker image manifest (Schema 2)
+// Manifest represents an image manifest or manifest list
+// It covers both Docker schema 2 and OCI documents loosely; use OCIManifest and
+// OCIIndex for the exact OCI structure.
 type Manifest struct {
 	SchemaVersion int             `json:"schemaVersion"`
 	MediaType     string          `json:"mediaType"`
@@ -19,77 +22,112 @@ type Manifest struct {
 
 // ManifestEntry represents an entry in a manifest list
 type ManifestEntry struct {
-	MediaType string `json:"mediaType"`
-	Digest    string `json:"digest"`
-	Platform  Platform `json:"platform"`
+	MediaType    string            `json:"mediaType"`
+	Size         int64             `json:"size"`
+	Digest       string            `json:"digest"`
+	Platform     Platform          `json:"platform"`
+	ArtifactType string            `json:"artifactType,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
 }
 
 // Platform represents the platform in a manifest list
 type Platform struct {
-	Architecture string `json:"architecture"`
-	OS           string `json:"os"`
-	Variant      string `json:"variant,omitempty"`
+	Architecture string   `json:"architecture"`
+	OS           string   `json:"os"`
+	OSVersion    string   `json:"os.version,omitempty"`
+	OSFeatures   []string `json:"os.features,omitempty"`
+	Variant      string   `json:"variant,omitempty"`
 }
 
 // Layer represents a config or layer in a manifest
-type Layer struct {
-	MediaType string `json:"mediaType"`
-	Size      int64  `json:"size"`
-	Digest    string `json:"digest"`
-}
+type Layer = Descriptor
 
-// GetManifest fetches the manifest for an image
+// GetManifest fetches the manifest for an image, resolving manifest lists to the host platform
 func (c *Client) GetManifest(name, reference string) (*Manifest, error) {
-	path := fmt.Sprintf("/%s/manifests/%s", name, reference)
-	headers := map[string]string{
-		"Accept": "application/vnd.docker.distribution.manifest.v2+json,application/vnd.docker.distribution.manifest.list.v2+json",
+	return c.GetManifestForPlatform(name, reference, DefaultPlatform())
+}
+
+// GetManifestForPlatform fetches the manifest for an image
+// If the reference points to a manifest list or OCI image index, the manifest
+// for the given platform is returned instead.
+func (c *Client) GetManifestForPlatform(name, reference string, platform Platform) (*Manifest, error) {
+	body, _, err := c.GetPlatformManifestRaw(name, reference, platform)
+	if err != nil {
+		return nil, err
 	}
 
-	resp, err := c.doRequest("GET", path, headers)
+	// Parse manifest
+	var manifest Manifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	return &manifest, nil
+}
+
+// GetPlatformManifestRaw fetches the raw bytes of the manifest for reference
+// If the reference points to a manifest list or OCI image index, the manifest
+// for the given platform is fetched instead.
+func (c *Client) GetPlatformManifestRaw(name, reference string, platform Platform) ([]byte, string, error) {
+	body, contentType, err := c.GetManifestRaw(name, reference)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get manifest: %w", err)
+		return nil, "", err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+	if !IsManifestList(DetectMediaType(body, contentType)) {
+		return body, contentType, nil
 	}
 
-	// Get content type
-	contentType := resp.Header.Get("Content-Type")
+	// Resolve the manifest list to the entry for our platform
+	var index Manifest
+	if err := json.Unmarshal(body, &index); err != nil {
+		return nil, "", fmt.Errorf("failed to decode manifest list: %w", err)
+	}
 
-	// Parse manifest
-	var manifest Manifest
-	if err := json.NewDecoder(resp.Body).Decode(&manifest); err != nil {
-		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	entry, err := SelectManifest(index.Manifests, platform)
+	if err != nil {
+		return nil, "", err
 	}
 
-	// If it's a manifest list or OCI image index, resolve to the specific manifest for our platform
-	isManifestList := manifest.MediaType == "application/vnd.docker.distribution.manifest.list.v2+json" ||
-	                  contentType == "application/vnd.docker.distribution.manifest.list.v2+json" ||
-	                  manifest.MediaType == "application/vnd.oci.image.index.v1+json" ||
-	                  contentType == "application/vnd.oci.image.index.v1+json"
+	return c.GetManifestRaw(name, entry.Digest)
+}
 
-	if isManifestList {
-		// Find amd64 manifest
-		for _, m := range manifest.Manifests {
-			if m.Platform.Architecture == "amd64" && m.Platform.OS == "linux" {
-				// Fetch the actual manifest
-				return c.GetManifest(name, m.Digest)
-			}
-		}
-		return nil, fmt.Errorf("no amd64 manifest found in manifest list")
+// DetectMediaType returns the media type of a manifest
+// The mediaType field of the document is preferred over the Content-Type the
+// registry sent. OCI documents may omit both, in which case the media type is
+// inferred from the fields present.
+func DetectMediaType(body []byte, contentType string) string {
+	var doc struct {
+		MediaType string            `json:"mediaType"`
+		Config    *json.RawMessage  `json:"config"`
+		Manifests []json.RawMessage `json:"manifests"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return ""
+	}
+	if doc.MediaType != "" {
+		return doc.MediaType
 	}
 
-	return &manifest, nil
+	// Strip parameters such as "; charset=utf-8"
+	if idx := strings.Index(contentType, ";"); idx != -1 {
+		contentType = contentType[:idx]
+	}
+	if contentType = strings.TrimSpace(contentType); contentType != "" {
+		return contentType
+	}
+
+	if doc.Manifests != nil && doc.Config == nil {
+		return MediaTypeOCIIndex
+	}
+	return MediaTypeOCIManifest
 }
 
 // GetManifestRaw fetches the raw manifest bytes
 func (c *Client) GetManifestRaw(name, reference string) ([]byte, string, error) {
 	path := fmt.Sprintf("/%s/manifests/%s", name, reference)
 	headers := map[string]string{
-		"Accept": "application/vnd.docker.distribution.manifest.v2+json,application/vnd.docker.distribution.manifest.list.v2+json",
+		"Accept": manifestAccept,
 	}
 
 	resp, err := c.doRequest("GET", path, headers)
@@ -112,14 +150,44 @@ func (c *Client) GetManifestRaw(name, reference string) ([]byte, string, error)
 		return nil, "", fmt.Errorf("failed to read response body: %w", err)
 	}
 
+	// Verify the manifest digest
+	if err := verifyManifest(reference, body, resp); err != nil {
+		return nil, "", err
+	}
+
 	return body, contentType, nil
 }
 
+// verifyManifest checks manifest bytes against the requested digest and
+// against the Docker-Content-Digest header when the registry sends one
+func verifyManifest(reference string, body []byte, resp *http.Response) error {
+	// Fetched by digest: the content must hash to exactly that digest
+	if isDigest(reference) {
+		if err := VerifyDigest(reference, body); err != nil {
+			return fmt.Errorf("manifest verification failed: %w", err)
+		}
+	}
+
+	// The header is optional; only check it when it uses an algorithm we know
+	if headerDigest := resp.Header.Get("Docker-Content-Digest"); headerDigest != "" {
+		verifier, err := newDigestVerifier(headerDigest)
+		if err != nil {
+			return nil
+		}
+		verifier.Write(body)
+		if err := verifier.Verify(); err != nil {
+			return fmt.Errorf("manifest verification failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
 // GetManifestDigest gets the digest of a manifest
 func (c *Client) GetManifestDigest(name, reference string) (string, error) {
 	path := fmt.Sprintf("/%s/manifests/%s", name, reference)
 	headers := map[string]string{
-		"Accept": "application/vnd.docker.distribution.manifest.v2+json",
+		"Accept": manifestAccept,
 	}
 
 	resp, err := c.doRequest("HEAD", path, headers)
@@ -145,23 +213,17 @@ func (c *Client) GetManifestDigest(name, reference string) (string, error) {
 func (c *Client) PutManifest(name, reference string, manifest []byte, contentType string) error {
 	path := fmt.Sprintf("/%s/manifests/%s", name, reference)
 
-	// Create request with manifest body
-	fullURL := c.baseURL + "/v2" + path
-	req, err := http.NewRequest("PUT", fullURL, bytes.NewReader(manifest))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
 	// Add headers
-	req.Header.Set("Content-Type", contentType)
-	req.Header.Set("Content-Length", fmt.Sprintf("%d", len(manifest)))
-	c.auth.AddAuth(req)
-	req.Header.Set("Docker-Distribution-API-Version", "registry/2.0")
+	headers := map[string]string{
+		"Content-Type":   contentType,
+		"Content-Length": fmt.Sprintf("%d", len(manifest)),
+	}
 
-	// Send request
-	resp, err := c.httpClient.Do(req)
+	// Send request with manifest body
+	fullURL := c.baseURL + "/v2" + path
+	resp, err := c.send("PUT", fullURL, headers, bytes.NewReader(manifest), int64(len(manifest)))
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		return err
 	}
 	defer resp.Body.Close()
 
@@ -0,0 +1,53 @@
+package registry
+
+import "testing"
+
+func TestDetectMediaType(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		want        string
+	}{
+		{
+			name:        "mediaType field wins over Content-Type",
+			body:        `{"schemaVersion": 2, "mediaType": "application/vnd.docker.distribution.manifest.v2+json", "config": {}}`,
+			contentType: MediaTypeOCIManifest,
+			want:        MediaTypeDockerManifest,
+		},
+		{
+			name:        "Content-Type without parameters",
+			body:        `{"schemaVersion": 2, "manifests": []}`,
+			contentType: "application/vnd.docker.distribution.manifest.list.v2+json; charset=utf-8",
+			want:        MediaTypeDockerManifestList,
+		},
+		{
+			name: "OCI index inferred from manifests",
+			body: `{"schemaVersion": 2, "manifests": [{"digest": "sha256:abc"}]}`,
+			want: MediaTypeOCIIndex,
+		},
+		{
+			name: "OCI manifest inferred from config",
+			body: `{"schemaVersion": 2, "config": {"digest": "sha256:abc"}, "layers": []}`,
+			want: MediaTypeOCIManifest,
+		},
+		{
+			name:        "blank Content-Type is ignored",
+			body:        `{"schemaVersion": 2, "config": {}}`,
+			contentType: "  ",
+			want:        MediaTypeOCIManifest,
+		},
+		{
+			name:        "invalid JSON",
+			body:        `not a manifest`,
+			contentType: MediaTypeOCIManifest,
+			want:        "",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := DetectMediaType([]byte(tt.body), tt.contentType); got != tt.want {
+			t.Errorf("%s: DetectMediaType = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
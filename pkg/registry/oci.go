@@ -0,0 +1,84 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Descriptor describes content by media type, digest and size
+// It is the OCI content descriptor; Docker schema 2 uses the same fields.
+type Descriptor struct {
+	MediaType    string            `json:"mediaType"`
+	Size         int64             `json:"size"`
+	Digest       string            `json:"digest"`
+	URLs         []string          `json:"urls,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	Platform     *Platform         `json:"platform,omitempty"`
+	ArtifactType string            `json:"artifactType,omitempty"`
+}
+
+// OCIManifest represents an OCI image manifest
+// Artifacts use the same structure with an artifactType or a non-image config.
+type OCIManifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType,omitempty"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        Descriptor        `json:"config"`
+	Layers        []Descriptor      `json:"layers"`
+	Subject       *Descriptor       `json:"subject,omitempty"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// OCIIndex represents an OCI image index
+type OCIIndex struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType,omitempty"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Manifests     []Descriptor      `json:"manifests"`
+	Subject       *Descriptor       `json:"subject,omitempty"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// ParseOCIManifest parses an OCI image manifest or a Docker schema 2 manifest,
+// which shares its structure
+func ParseOCIManifest(data []byte) (*OCIManifest, error) {
+	var manifest OCIManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	if manifest.SchemaVersion != 2 {
+		return nil, fmt.Errorf("unsupported manifest schema version %d", manifest.SchemaVersion)
+	}
+	if manifest.MediaType != "" && !IsImageManifest(manifest.MediaType) {
+		return nil, fmt.Errorf("not an image manifest: %s", manifest.MediaType)
+	}
+
+	return &manifest, nil
+}
+
+// ParseOCIIndex parses an OCI image index or a Docker manifest list,
+// which shares its structure
+func ParseOCIIndex(data []byte) (*OCIIndex, error) {
+	var index OCIIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to decode index: %w", err)
+	}
+
+	if index.SchemaVersion != 2 {
+		return nil, fmt.Errorf("unsupported index schema version %d", index.SchemaVersion)
+	}
+	if index.MediaType != "" && !IsManifestList(index.MediaType) {
+		return nil, fmt.Errorf("not an image index: %s", index.MediaType)
+	}
+
+	return &index, nil
+}
+
+// IsImage reports whether a manifest describes a container image rather than another kind of artifact
+func (m *OCIManifest) IsImage() bool {
+	if m.ArtifactType != "" {
+		return false
+	}
+	return m.Config.MediaType == MediaTypeOCIConfig || m.Config.MediaType == MediaTypeDockerConfig
+}
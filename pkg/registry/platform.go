@@ -0,0 +1,218 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// DefaultPlatform returns the platform of the host timage is running on
+func DefaultPlatform() Platform {
+	return Platform{
+		OS:           runtime.GOOS,
+		Architecture: runtime.GOARCH,
+	}.Normalize()
+}
+
+// ParsePlatform parses a platform in the form os/arch[/variant], e.g. linux/arm/v7
+func ParsePlatform(s string) (Platform, error) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(s)), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return Platform{}, fmt.Errorf("invalid platform %q, expected os/arch[/variant]", s)
+	}
+
+	platform := Platform{
+		OS:           parts[0],
+		Architecture: parts[1],
+	}
+	if len(parts) == 3 {
+		platform.Variant = parts[2]
+	}
+
+	return platform.Normalize(), nil
+}
+
+// String formats the platform as os/arch[/variant]
+func (p Platform) String() string {
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	return s
+}
+
+// Normalize maps architecture aliases to their canonical names and fills in
+// the default variant, so platforms can be compared directly
+// e.g. aarch64 -> arm64/v8, armhf -> arm/v7, x86_64 -> amd64
+func (p Platform) Normalize() Platform {
+	p.OS = strings.ToLower(p.OS)
+	p.Architecture = strings.ToLower(p.Architecture)
+	p.Variant = strings.ToLower(p.Variant)
+
+	switch p.Architecture {
+	case "x86_64", "x86-64":
+		p.Architecture = "amd64"
+	case "i386", "i686":
+		p.Architecture = "386"
+	case "aarch64":
+		p.Architecture = "arm64"
+	case "armhf":
+		p.Architecture = "arm"
+		p.Variant = "v7"
+	case "armel":
+		p.Architecture = "arm"
+		p.Variant = "v6"
+	}
+
+	// Variants are sometimes written without the leading "v"
+	if p.Variant != "" && p.Variant[0] >= '0' && p.Variant[0] <= '9' {
+		p.Variant = "v" + p.Variant
+	}
+
+	switch p.Architecture {
+	case "arm64":
+		if p.Variant == "" {
+			p.Variant = "v8"
+		}
+	case "arm":
+		if p.Variant == "" {
+			p.Variant = "v7"
+		}
+	case "amd64":
+		if p.Variant == "v1" {
+			p.Variant = ""
+		}
+	}
+
+	return p
+}
+
+// compatibleVariants returns the variants a platform can run, best match first
+// An arm/v7 host can run v6 and v5 images, an amd64/v3 host can run v2 and v1 images.
+func (p Platform) compatibleVariants() []string {
+	var order []string
+	switch p.Architecture {
+	case "arm":
+		order = []string{"v8", "v7", "v6", "v5"}
+	case "amd64":
+		order = []string{"v4", "v3", "v2", ""}
+	default:
+		return []string{p.Variant}
+	}
+
+	for i, variant := range order {
+		if variant == p.Variant {
+			return order[i:]
+		}
+	}
+	return []string{p.Variant}
+}
+
+// SelectManifest picks the manifest list entry that best matches platform
+// Exact variant matches are preferred over older compatible variants. If nothing
+// matches, the error lists the platforms that are available.
+func SelectManifest(entries []ManifestEntry, platform Platform) (*ManifestEntry, error) {
+	platform = platform.Normalize()
+
+	for _, variant := range platform.compatibleVariants() {
+		for i := range entries {
+			candidate := entries[i].Platform.Normalize()
+			if candidate.OS == platform.OS &&
+				candidate.Architecture == platform.Architecture &&
+				candidate.Variant == variant {
+				return &entries[i], nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no manifest for platform %s; available platforms: %s",
+		platform, availablePlatforms(entries))
+}
+
+// availablePlatforms lists the platforms of the images in a manifest list
+func availablePlatforms(entries []ManifestEntry) string {
+	var available []string
+	for _, entry := range entries {
+		// Skip attestation manifests and other non-image entries
+		if entry.Platform.OS == "" || entry.Platform.OS == "unknown" {
+			continue
+		}
+		available = append(available, entry.Platform.String())
+	}
+	return strings.Join(available, ", ")
+}
+
+// FilterIndex reduces a manifest list or index to the images for the given platforms
+// Each platform is matched the way SelectManifest does; platforms the index has no
+// image for are returned as missing, and it is an error only if none of them matches.
+// Attestation manifests are kept for the images that remain. If every entry is kept
+// the data is returned unchanged, so the digest only changes when the index really does.
+func FilterIndex(data []byte, platforms []Platform) ([]byte, []Platform, error) {
+	var index Manifest
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode manifest list: %w", err)
+	}
+
+	keep := make(map[string]bool)
+	var missing []Platform
+	var selectErr error
+	for _, platform := range platforms {
+		entry, err := SelectManifest(index.Manifests, platform)
+		if err != nil {
+			missing = append(missing, platform)
+			selectErr = err
+			continue
+		}
+		keep[entry.Digest] = true
+	}
+	if len(keep) == 0 && len(platforms) == 1 {
+		return nil, nil, selectErr
+	}
+	if len(keep) == 0 {
+		names := make([]string, len(platforms))
+		for i, platform := range platforms {
+			names[i] = platform.String()
+		}
+		return nil, nil, fmt.Errorf("no manifest for any of the platforms %s; available platforms: %s",
+			strings.Join(names, ", "), availablePlatforms(index.Manifests))
+	}
+	for _, entry := range index.Manifests {
+		if ref := entry.Annotations["vnd.docker.reference.digest"]; ref != "" && keep[ref] {
+			keep[entry.Digest] = true
+		}
+	}
+
+	if len(keep) == len(index.Manifests) {
+		return data, missing, nil
+	}
+
+	// Rewrite only the manifests field so everything else is preserved as is
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode manifest list: %w", err)
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal(doc["manifests"], &entries); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode manifest list: %w", err)
+	}
+
+	var kept []json.RawMessage
+	for i, entry := range entries {
+		if keep[index.Manifests[i].Digest] {
+			kept = append(kept, entry)
+		}
+	}
+
+	manifests, err := json.Marshal(kept)
+	if err != nil {
+		return nil, nil, err
+	}
+	doc["manifests"] = manifests
+
+	filtered, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+	return filtered, missing, nil
+}
@@ -0,0 +1,255 @@
+package registry
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPlatformNormalize(t *testing.T) {
+	tests := []struct {
+		in   Platform
+		want Platform
+	}{
+		{Platform{OS: "linux", Architecture: "amd64"}, Platform{OS: "linux", Architecture: "amd64"}},
+		{Platform{OS: "Linux", Architecture: "X86_64"}, Platform{OS: "linux", Architecture: "amd64"}},
+		{Platform{OS: "linux", Architecture: "amd64", Variant: "v1"}, Platform{OS: "linux", Architecture: "amd64"}},
+		{Platform{OS: "linux", Architecture: "amd64", Variant: "3"}, Platform{OS: "linux", Architecture: "amd64", Variant: "v3"}},
+		{Platform{OS: "linux", Architecture: "i686"}, Platform{OS: "linux", Architecture: "386"}},
+		{Platform{OS: "linux", Architecture: "aarch64"}, Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}},
+		{Platform{OS: "linux", Architecture: "arm64"}, Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}},
+		{Platform{OS: "linux", Architecture: "arm"}, Platform{OS: "linux", Architecture: "arm", Variant: "v7"}},
+		{Platform{OS: "linux", Architecture: "arm", Variant: "6"}, Platform{OS: "linux", Architecture: "arm", Variant: "v6"}},
+		{Platform{OS: "linux", Architecture: "armhf"}, Platform{OS: "linux", Architecture: "arm", Variant: "v7"}},
+		{Platform{OS: "linux", Architecture: "armel"}, Platform{OS: "linux", Architecture: "arm", Variant: "v6"}},
+	}
+
+	for _, tt := range tests {
+		if got := tt.in.Normalize(); got.String() != tt.want.String() {
+			t.Errorf("%+v.Normalize() = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParsePlatform(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "linux/amd64", want: "linux/amd64"},
+		{in: " Linux/ARM64 ", want: "linux/arm64/v8"},
+		{in: "linux/arm/v6", want: "linux/arm/v6"},
+		{in: "linux/arm/7", want: "linux/arm/v7"},
+		{in: "linux", wantErr: true},
+		{in: "linux/", wantErr: true},
+		{in: "/amd64", wantErr: true},
+		{in: "linux/arm/v7/extra", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePlatform(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParsePlatform(%q) = %s, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParsePlatform(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("ParsePlatform(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSelectManifest(t *testing.T) {
+	entries := []ManifestEntry{
+		{Digest: "sha256:amd64", Platform: Platform{OS: "linux", Architecture: "amd64"}},
+		{Digest: "sha256:amd64v3", Platform: Platform{OS: "linux", Architecture: "amd64", Variant: "v3"}},
+		{Digest: "sha256:armv6", Platform: Platform{OS: "linux", Architecture: "arm", Variant: "v6"}},
+		{Digest: "sha256:arm64", Platform: Platform{OS: "linux", Architecture: "aarch64"}},
+		{Digest: "sha256:attestation", Platform: Platform{OS: "unknown", Architecture: "unknown"}},
+	}
+
+	tests := []struct {
+		platform string
+		want     string
+	}{
+		{"linux/amd64", "sha256:amd64"},
+		{"linux/amd64/v3", "sha256:amd64v3"},
+		// Falls back to the newest variant the platform can run
+		{"linux/amd64/v2", "sha256:amd64"},
+		{"linux/amd64/v4", "sha256:amd64v3"},
+		{"linux/arm/v7", "sha256:armv6"},
+		{"linux/arm/v6", "sha256:armv6"},
+		// Aliases on either side are normalized before comparing
+		{"linux/arm64", "sha256:arm64"},
+		{"linux/arm64/v8", "sha256:arm64"},
+	}
+
+	for _, tt := range tests {
+		platform, err := ParsePlatform(tt.platform)
+		if err != nil {
+			t.Fatalf("ParsePlatform(%q) failed: %v", tt.platform, err)
+		}
+		entry, err := SelectManifest(entries, platform)
+		if err != nil {
+			t.Errorf("SelectManifest(%s) failed: %v", tt.platform, err)
+			continue
+		}
+		if entry.Digest != tt.want {
+			t.Errorf("SelectManifest(%s) = %s, want %s", tt.platform, entry.Digest, tt.want)
+		}
+	}
+}
+
+func TestSelectManifestMissing(t *testing.T) {
+	entries := []ManifestEntry{
+		{Digest: "sha256:armv7", Platform: Platform{OS: "linux", Architecture: "arm", Variant: "v7"}},
+		{Digest: "sha256:attestation", Platform: Platform{OS: "unknown", Architecture: "unknown"}},
+	}
+
+	for _, platform := range []Platform{
+		{OS: "linux", Architecture: "amd64"},
+		{OS: "windows", Architecture: "arm", Variant: "v7"},
+		// An older variant can't run a newer image
+		{OS: "linux", Architecture: "arm", Variant: "v6"},
+	} {
+		entry, err := SelectManifest(entries, platform)
+		if err == nil {
+			t.Errorf("SelectManifest(%s) = %s, want an error", platform, entry.Digest)
+			continue
+		}
+		if !strings.Contains(err.Error(), "available platforms: linux/arm/v7") ||
+			strings.Contains(err.Error(), "unknown") {
+			t.Errorf("SelectManifest(%s) error %q doesn't list the available platforms", platform, err)
+		}
+	}
+}
+
+func TestFilterIndex(t *testing.T) {
+	index := []byte(`{
+  "schemaVersion": 2,
+  "mediaType": "application/vnd.oci.image.index.v1+json",
+  "manifests": [
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:amd64", "size": 1, "platform": {"os": "linux", "architecture": "amd64"}},
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:armv7", "size": 1, "platform": {"os": "linux", "architecture": "arm", "variant": "v7"}},
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:arm64", "size": 1, "platform": {"os": "linux", "architecture": "arm64", "variant": "v8"}},
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:att", "size": 1, "platform": {"os": "unknown", "architecture": "unknown"},
+     "annotations": {"vnd.docker.reference.digest": "sha256:amd64", "vnd.docker.reference.type": "attestation-manifest"}}
+  ],
+  "annotations": {"org.opencontainers.image.created": "2024-01-01T00:00:00Z"}
+}`)
+
+	tests := []struct {
+		name      string
+		platforms []string
+		want      []string
+		missing   []string
+		unchanged bool
+	}{
+		{
+			name:      "single platform keeps its attestation",
+			platforms: []string{"linux/amd64"},
+			want:      []string{"sha256:amd64", "sha256:att"},
+		},
+		{
+			name:      "attestation dropped with its image",
+			platforms: []string{"linux/arm64"},
+			want:      []string{"sha256:arm64"},
+		},
+		{
+			name:      "variant fallback",
+			platforms: []string{"linux/arm/v8"},
+			want:      []string{"sha256:armv7"},
+		},
+		{
+			name:      "every platform returns the index unchanged",
+			platforms: []string{"linux/amd64", "linux/arm/v7", "linux/arm64"},
+			want:      []string{"sha256:amd64", "sha256:armv7", "sha256:arm64", "sha256:att"},
+			unchanged: true,
+		},
+		{
+			name:      "missing platform is skipped",
+			platforms: []string{"linux/s390x", "linux/arm64"},
+			want:      []string{"sha256:arm64"},
+			missing:   []string{"linux/s390x"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered, missing, err := FilterIndex(index, parsePlatforms(t, tt.platforms))
+			if err != nil {
+				t.Fatalf("FilterIndex failed: %v", err)
+			}
+
+			var gotMissing []string
+			for _, platform := range missing {
+				gotMissing = append(gotMissing, platform.String())
+			}
+			if strings.Join(gotMissing, ",") != strings.Join(tt.missing, ",") {
+				t.Errorf("missing = %v, want %v", gotMissing, tt.missing)
+			}
+
+			if tt.unchanged && string(filtered) != string(index) {
+				t.Errorf("index was rewritten although every entry is kept")
+			}
+
+			var out struct {
+				Manifests   []ManifestEntry   `json:"manifests"`
+				Annotations map[string]string `json:"annotations"`
+			}
+			if err := json.Unmarshal(filtered, &out); err != nil {
+				t.Fatalf("filtered index doesn't decode: %v", err)
+			}
+			var got []string
+			for _, entry := range out.Manifests {
+				got = append(got, entry.Digest)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("manifests = %v, want %v", got, tt.want)
+			}
+			if out.Annotations["org.opencontainers.image.created"] == "" {
+				t.Errorf("index annotations were not preserved")
+			}
+		})
+	}
+}
+
+func TestFilterIndexNoMatch(t *testing.T) {
+	index := []byte(`{"schemaVersion": 2, "manifests": [
+    {"digest": "sha256:amd64", "platform": {"os": "linux", "architecture": "amd64"}}
+  ]}`)
+
+	tests := []struct {
+		platforms []string
+		want      string
+	}{
+		{[]string{"linux/s390x"}, "no manifest for platform linux/s390x; available platforms: linux/amd64"},
+		{[]string{"linux/s390x", "windows/amd64"}, "no manifest for any of the platforms linux/s390x, windows/amd64; available platforms: linux/amd64"},
+	}
+
+	for _, tt := range tests {
+		_, _, err := FilterIndex(index, parsePlatforms(t, tt.platforms))
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("FilterIndex(%v) error = %v, want %q", tt.platforms, err, tt.want)
+		}
+	}
+}
+
+func parsePlatforms(t *testing.T, names []string) []Platform {
+	t.Helper()
+	platforms := make([]Platform, len(names))
+	for i, name := range names {
+		platform, err := ParsePlatform(name)
+		if err != nil {
+			t.Fatalf("ParsePlatform(%q) failed: %v", name, err)
+		}
+		platforms[i] = platform
+	}
+	return platforms
+}
@@ -0,0 +1,109 @@
+package registry
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{value: "", wantOK: false},
+		{value: "soon", wantOK: false},
+		{value: "0", want: 0, wantOK: true},
+		{value: "7", want: 7 * time.Second, wantOK: true},
+		{value: "-3", want: 0, wantOK: true},
+		{value: "86400", want: maxRetryAfter, wantOK: true},
+		{value: time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), want: 0, wantOK: true},
+		{value: time.Now().Add(24 * time.Hour).UTC().Format(http.TimeFormat), want: maxRetryAfter, wantOK: true},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	// An HTTP date is relative to now, so allow for the time the test takes
+	date := time.Now().Add(90 * time.Second).UTC().Format(http.TimeFormat)
+	got, ok := parseRetryAfter(date)
+	if !ok || got < 85*time.Second || got > 90*time.Second {
+		t.Errorf("parseRetryAfter(%q) = %v, %v, want about 90s", date, got, ok)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: time.Second,
+		MaxBackoff:     10 * time.Second,
+	}
+
+	tests := []struct {
+		attempt    int
+		retryAfter string
+		// The backoff is jittered between half and all of max
+		max time.Duration
+	}{
+		{attempt: 1, max: time.Second},
+		{attempt: 2, max: 2 * time.Second},
+		{attempt: 3, max: 4 * time.Second},
+		{attempt: 4, max: 8 * time.Second},
+		{attempt: 5, max: 10 * time.Second},
+		{attempt: 30, max: 10 * time.Second},
+		// Retry-After is used as is, without jitter or the backoff cap
+		{attempt: 1, retryAfter: "20", max: 20 * time.Second},
+		{attempt: 1, retryAfter: "bogus", max: time.Second},
+	}
+
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		if tt.retryAfter != "" {
+			resp.Header.Set("Retry-After", tt.retryAfter)
+		}
+
+		min := tt.max / 2
+		if tt.retryAfter != "" && tt.retryAfter != "bogus" {
+			min = tt.max
+		}
+		for i := 0; i < 20; i++ {
+			if got := policy.backoff(tt.attempt, resp); got < min || got > tt.max {
+				t.Errorf("backoff(%d, Retry-After %q) = %v, want between %v and %v",
+					tt.attempt, tt.retryAfter, got, min, tt.max)
+				break
+			}
+		}
+	}
+
+	if got := (RetryPolicy{}).backoff(1, nil); got != 0 {
+		t.Errorf("backoff without an initial backoff = %v, want 0", got)
+	}
+}
+
+func TestIsRetryableStatus(t *testing.T) {
+	tests := []struct {
+		status int
+		want   bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusNotFound, false},
+		{http.StatusUnauthorized, false},
+		{http.StatusTooManyRequests, true},
+		{http.StatusInternalServerError, true},
+		{http.StatusNotImplemented, false},
+		{http.StatusBadGateway, true},
+		{http.StatusServiceUnavailable, true},
+		{http.StatusGatewayTimeout, true},
+	}
+
+	for _, tt := range tests {
+		if got := isRetryableStatus(tt.status); got != tt.want {
+			t.Errorf("isRetryableStatus(%d) = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package registry
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// TagFilter selects tags of a repository
+// Tags matching Regex and Semver (when set) are selected, and of those only the
+// Latest newest semantic versions are kept (when set). Exact tags are always selected.
+// A filter with only exact tags selects just those; an empty filter selects every tag.
+type TagFilter struct {
+	Exact  []string
+	Regex  string
+	Semver string
+	Latest int
+
+	regex      *regexp.Regexp
+	constraint *semver.Constraints
+}
+
+// Compile validates the regular expression and semver range of the filter
+func (f *TagFilter) Compile() error {
+	if f.Regex != "" {
+		regex, err := regexp.Compile(f.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid tag regex: %w", err)
+		}
+		f.regex = regex
+	}
+
+	if f.Semver != "" {
+		constraint, err := semver.NewConstraint(f.Semver)
+		if err != nil {
+			return fmt.Errorf("invalid semver range %q: %w", f.Semver, err)
+		}
+		f.constraint = constraint
+	}
+
+	if f.Latest < 0 {
+		return fmt.Errorf("invalid latest count %d", f.Latest)
+	}
+
+	return nil
+}
+
+// NeedsTagList reports whether applying the filter needs the repository's tag list
+func (f *TagFilter) NeedsTagList() bool {
+	return len(f.Exact) == 0 || f.Regex != "" || f.Semver != "" || f.Latest > 0
+}
+
+// Apply returns the tags selected by the filter, in the order of tags followed by
+// exact tags that were not in it
+func (f *TagFilter) Apply(tags []string) ([]string, error) {
+	if err := f.Compile(); err != nil {
+		return nil, err
+	}
+
+	var selected []string
+	if f.NeedsTagList() {
+		for _, tag := range tags {
+			if f.regex != nil && !f.regex.MatchString(tag) {
+				continue
+			}
+			if f.constraint != nil {
+				version, err := semver.NewVersion(tag)
+				if err != nil || !f.constraint.Check(version) {
+					continue
+				}
+			}
+			selected = append(selected, tag)
+		}
+
+		if f.Latest > 0 {
+			selected = latestVersions(selected, f.Latest)
+		}
+	}
+
+	// Exact tags are selected whether or not the other filters match them
+	seen := make(map[string]bool)
+	for _, tag := range selected {
+		seen[tag] = true
+	}
+	for _, tag := range f.Exact {
+		if !seen[tag] {
+			seen[tag] = true
+			selected = append(selected, tag)
+		}
+	}
+
+	return selected, nil
+}
+
+// latestVersions returns the n highest semantic versions among tags, keeping their order
+// Tags that are not semantic versions are left out.
+func latestVersions(tags []string, n int) []string {
+	type versionTag struct {
+		tag     string
+		version *semver.Version
+	}
+
+	var versions []versionTag
+	for _, tag := range tags {
+		if version, err := semver.NewVersion(tag); err == nil {
+			versions = append(versions, versionTag{tag: tag, version: version})
+		}
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].version.GreaterThan(versions[j].version)
+	})
+	if len(versions) > n {
+		versions = versions[:n]
+	}
+
+	keep := make(map[string]bool)
+	for _, v := range versions {
+		keep[v.tag] = true
+	}
+
+	var latest []string
+	for _, tag := range tags {
+		if keep[tag] {
+			latest = append(latest, tag)
+		}
+	}
+	return latest
+}
+
+// Tag sort orders
+const (
+	TagSortLexical = "lexical"
+	TagSortSemver  = "semver"
+)
+
+// SortTags sorts tags in place
+// In semver order tags are sorted by semantic version, oldest first, followed by
+// the tags that are not semantic versions in lexical order.
+func SortTags(tags []string, order string) error {
+	switch order {
+	case TagSortLexical:
+		sort.Strings(tags)
+	case TagSortSemver:
+		versions := make(map[string]*semver.Version)
+		for _, tag := range tags {
+			if version, err := semver.NewVersion(tag); err == nil {
+				versions[tag] = version
+			}
+		}
+
+		sort.SliceStable(tags, func(i, j int) bool {
+			vi, vj := versions[tags[i]], versions[tags[j]]
+			switch {
+			case vi != nil && vj != nil:
+				if vi.Equal(vj) {
+					return tags[i] < tags[j]
+				}
+				return vi.LessThan(vj)
+			case vi != nil:
+				return true
+			case vj != nil:
+				return false
+			}
+			return tags[i] < tags[j]
+		})
+	default:
+		return fmt.Errorf("unknown sort order %q (expected %s or %s)", order, TagSortLexical, TagSortSemver)
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package registry
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTagFilterApply(t *testing.T) {
+	tags := []string{"latest", "1.2.0", "1.10.0", "v2.0.0", "2.1.0-rc1", "1.9.3", "edge", "3.0"}
+
+	tests := []struct {
+		name    string
+		filter  TagFilter
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "empty filter selects every tag",
+			filter: TagFilter{},
+			want:   tags,
+		},
+		{
+			name:   "exact tags only",
+			filter: TagFilter{Exact: []string{"edge", "missing"}},
+			want:   []string{"edge", "missing"},
+		},
+		{
+			name:   "regex",
+			filter: TagFilter{Regex: `^1\.`},
+			want:   []string{"1.2.0", "1.10.0", "1.9.3"},
+		},
+		{
+			name:   "semver range skips pre-releases and non-versions",
+			filter: TagFilter{Semver: ">=1.9"},
+			want:   []string{"1.10.0", "v2.0.0", "1.9.3", "3.0"},
+		},
+		{
+			name:   "latest keeps tag order",
+			filter: TagFilter{Latest: 3},
+			want:   []string{"v2.0.0", "2.1.0-rc1", "3.0"},
+		},
+		{
+			name:   "regex and latest combined",
+			filter: TagFilter{Regex: `^1\.`, Latest: 2},
+			want:   []string{"1.10.0", "1.9.3"},
+		},
+		{
+			name:   "exact tags are added to the matches",
+			filter: TagFilter{Semver: "~1.9", Exact: []string{"latest", "1.9.3"}},
+			want:   []string{"1.9.3", "latest"},
+		},
+		{
+			name:    "invalid regex",
+			filter:  TagFilter{Regex: `(`},
+			wantErr: true,
+		},
+		{
+			name:    "invalid semver range",
+			filter:  TagFilter{Semver: "not a range"},
+			wantErr: true,
+		},
+		{
+			name:    "negative latest",
+			filter:  TagFilter{Latest: -1},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.filter.Apply(tags)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Apply = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Apply = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortTags(t *testing.T) {
+	tests := []struct {
+		order   string
+		want    []string
+		wantErr bool
+	}{
+		{order: TagSortLexical, want: []string{"1.10.0", "1.2.0", "1.9.3", "edge", "latest", "v1.2.0"}},
+		{order: TagSortSemver, want: []string{"1.2.0", "v1.2.0", "1.9.3", "1.10.0", "edge", "latest"}},
+		{order: "newest", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tags := []string{"latest", "1.10.0", "v1.2.0", "edge", "1.2.0", "1.9.3"}
+		err := SortTags(tags, tt.order)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("SortTags(%s) succeeded, want an error", tt.order)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("SortTags(%s) failed: %v", tt.order, err)
+			continue
+		}
+		if strings.Join(tags, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("SortTags(%s) = %v, want %v", tt.order, tags, tt.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// tagList is the response of the tags/list endpoint
+type tagList struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+// ListTags returns every tag of a repository, following Link-header pagination
+func (c *Client) ListTags(name string) ([]string, error) {
+	var tags []string
+
+	err := c.getPages(fmt.Sprintf("/%s/tags/list", name), func(body []byte) error {
+		var page tagList
+		if err := json.Unmarshal(body, &page); err != nil {
+			return fmt.Errorf("failed to decode tag list: %w", err)
+		}
+		tags = append(tags, page.Tags...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+// getPages fetches a paginated listing, calling handle with the body of every page
+// The next page is taken from the Link header with rel="next", as the distribution
+// spec describes; the listing ends when a response has none.
+func (c *Client) getPages(path string, handle func([]byte) error) error {
+	fullURL := c.baseURL + "/v2" + path
+	seen := make(map[string]bool)
+
+	for fullURL != "" {
+		// A registry repeating a link would otherwise keep us here forever
+		if seen[fullURL] {
+			return fmt.Errorf("pagination loop at %s", fullURL)
+		}
+		seen[fullURL] = true
+
+		resp, err := c.send("GET", fullURL, nil, nil, 0)
+		if err != nil {
+			return err
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+		}
+
+		if err := handle(body); err != nil {
+			return err
+		}
+
+		next := nextLink(resp.Header.Get("Link"))
+		if next == "" {
+			return nil
+		}
+		fullURL, err = c.resolveLocation(next)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nextLink returns the target of the rel="next" entry of a Link header, or ""
+// Format: <url>; rel="next"
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		parts := strings.Split(link, ";")
+		target := strings.TrimSpace(parts[0])
+		if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+
+		for _, param := range parts[1:] {
+			param = strings.ReplaceAll(strings.TrimSpace(param), " ", "")
+			if param == `rel="next"` || param == "rel=next" {
+				return target[1 : len(target)-1]
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,25 @@
+package registry
+
+import "testing"
+
+func TestNextLink(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{``, ``},
+		{`</v2/library/alpine/tags/list?last=3.19&n=100>; rel="next"`, `/v2/library/alpine/tags/list?last=3.19&n=100`},
+		{`<https://registry.example.com/v2/_catalog?last=b>; rel=next`, `https://registry.example.com/v2/_catalog?last=b`},
+		{`</v2/x/tags/list?last=a>;rel = "next"`, `/v2/x/tags/list?last=a`},
+		{`</first>; rel="prev", </second>; rel="next"`, `/second`},
+		{`</v2/x/tags/list?last=a>; rel="prev"`, ``},
+		{`/v2/x/tags/list?last=a; rel="next"`, ``},
+		{`</v2/x/tags/list?last=a>`, ``},
+	}
+
+	for _, tt := range tests {
+		if got := nextLink(tt.header); got != tt.want {
+			t.Errorf("nextLink(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
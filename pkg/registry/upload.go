@@ -0,0 +1,245 @@
+package registry
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SetUploadChunkSize makes UploadBlob send blobs in chunks of the given size
+// Zero restores monolithic uploads.
+func (c *Client) SetUploadChunkSize(size int64) {
+	c.chunkSize = size
+}
+
+// UploadBlobChunked uploads a blob as a series of PATCH requests followed by a final PUT
+// If a chunk fails, the upload session is queried for how much the registry has
+// received and the upload continues from there, up to the client's retry limit.
+func (c *Client) UploadBlobChunked(uploadURL string, reader io.ReaderAt, size int64, digest string, chunkSize int64) error {
+	if chunkSize <= 0 {
+		return fmt.Errorf("invalid chunk size: %d", chunkSize)
+	}
+
+	var offset int64
+	resumes := 0
+	for offset < size {
+		n := chunkSize
+		if offset+n > size {
+			n = size - offset
+		}
+
+		nextURL, err := c.uploadChunk(uploadURL, io.NewSectionReader(reader, offset, n), offset, n)
+		if err != nil {
+			// Ask the registry what it actually received and resume from there
+			resumes++
+			if resumes >= c.retry.MaxAttempts {
+				return err
+			}
+
+			status, statusErr := c.GetUploadStatus(uploadURL)
+			if statusErr != nil {
+				return fmt.Errorf("%w (failed to resume: %v)", err, statusErr)
+			}
+			uploadURL, offset = status.Location, status.Offset
+			continue
+		}
+
+		uploadURL = nextURL
+		offset += n
+	}
+
+	return c.completeUpload(uploadURL, digest)
+}
+
+// uploadStreamChunked uploads a blob read from a stream as a series of PATCH requests
+// followed by a final PUT
+// Each chunk is buffered in memory, so a chunk the registry only partly received can be
+// resumed like in UploadBlobChunked; earlier chunks can't be sent again.
+func (c *Client) uploadStreamChunked(uploadURL string, reader io.Reader, digest string, chunkSize int64) error {
+	if chunkSize <= 0 {
+		return fmt.Errorf("invalid chunk size: %d", chunkSize)
+	}
+
+	buf := make([]byte, chunkSize)
+	var offset int64
+	for {
+		n, err := io.ReadFull(reader, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+		chunk := buf[:n]
+
+		// Send the chunk, resuming from what the registry received if it fails
+		sent := int64(0)
+		resumes := 0
+		for sent < int64(n) {
+			rest := chunk[sent:]
+			nextURL, err := c.uploadChunk(uploadURL, bytes.NewReader(rest), offset+sent, int64(len(rest)))
+			if err != nil {
+				resumes++
+				if resumes >= c.retry.MaxAttempts {
+					return err
+				}
+
+				status, statusErr := c.GetUploadStatus(uploadURL)
+				if statusErr != nil {
+					return fmt.Errorf("%w (failed to resume: %v)", err, statusErr)
+				}
+				if status.Offset < offset || status.Offset > offset+int64(n) {
+					return fmt.Errorf("%w (registry has %d bytes, can't resume from a stream)", err, status.Offset)
+				}
+				uploadURL, sent = status.Location, status.Offset-offset
+				continue
+			}
+
+			uploadURL = nextURL
+			sent = int64(n)
+		}
+		offset += int64(n)
+
+		if n < len(buf) {
+			break
+		}
+	}
+
+	return c.completeUpload(uploadURL, digest)
+}
+
+// completeUpload closes an upload session once all data has been sent
+func (c *Client) completeUpload(uploadURL, digest string) error {
+	// All data has been sent so the body is empty
+	if strings.Contains(uploadURL, "?") {
+		uploadURL += "&digest=" + digest
+	} else {
+		uploadURL += "?digest=" + digest
+	}
+
+	headers := map[string]string{
+		"Content-Length": "0",
+	}
+
+	resp, err := c.send("PUT", uploadURL, headers, nil, 0)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// uploadChunk sends one chunk and returns the URL to use for the next request
+func (c *Client) uploadChunk(uploadURL string, chunk io.ReadSeeker, offset, n int64) (string, error) {
+	headers := map[string]string{
+		"Content-Type":   "application/octet-stream",
+		"Content-Range":  fmt.Sprintf("%d-%d", offset, offset+n-1),
+		"Content-Length": fmt.Sprintf("%d", n),
+	}
+
+	resp, err := c.send("PATCH", uploadURL, headers, chunk, n)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("chunk upload failed with status code %d: %s", resp.StatusCode, string(body))
+	}
+
+	// The session URL may change with every chunk
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return uploadURL, nil
+	}
+
+	return c.resolveLocation(location)
+}
+
+// UploadStatus describes the progress of an upload session
+type UploadStatus struct {
+	// Location is the URL to continue the upload with
+	Location string
+	// Offset is the number of bytes the registry has received
+	Offset int64
+}
+
+// GetUploadStatus queries an upload session for how much data the registry has received
+func (c *Client) GetUploadStatus(uploadURL string) (*UploadStatus, error) {
+	resp, err := c.send("GET", uploadURL, nil, nil, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload status: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	status := &UploadStatus{Location: uploadURL}
+	if location := resp.Header.Get("Location"); location != "" {
+		if status.Location, err = c.resolveLocation(location); err != nil {
+			return nil, err
+		}
+	}
+
+	// Range: 0-<last byte received>; "0-0" is also used for an empty session
+	var start, end int64
+	rangeHeader := strings.TrimPrefix(resp.Header.Get("Range"), "bytes=")
+	if _, err := fmt.Sscanf(rangeHeader, "%d-%d", &start, &end); err == nil && end > 0 {
+		status.Offset = end + 1
+	}
+
+	return status, nil
+}
+
+// MountBlob asks the registry to link a blob that already exists in another
+// repository on the same registry, avoiding an upload
+// It returns false when the registry can't mount the blob (unknown blob, no
+// permission on the source repository, or mounting unsupported).
+func (c *Client) MountBlob(name, digest, fromRepo string) (bool, error) {
+	path := fmt.Sprintf("/%s/blobs/uploads/?mount=%s&from=%s",
+		name, url.QueryEscape(digest), url.QueryEscape(fromRepo))
+
+	resp, err := c.doRequest("POST", path, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to mount blob: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		return true, nil
+	case http.StatusAccepted:
+		// The registry fell back to a regular upload session; we don't need it
+		if location := resp.Header.Get("Location"); location != "" {
+			c.cancelUpload(location)
+		}
+		return false, nil
+	default:
+		return false, nil
+	}
+}
+
+// cancelUpload cancels an upload session, ignoring errors
+func (c *Client) cancelUpload(location string) {
+	uploadURL, err := c.resolveLocation(location)
+	if err != nil {
+		return
+	}
+
+	resp, err := c.send("DELETE", uploadURL, nil, nil, 0)
+	if err == nil {
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// ArchiveWriter receives the files of an exported image archive
+// Names are slash-separated paths relative to the archive root.
+type ArchiveWriter interface {
+	// WriteFile adds a file with the given content
+	WriteFile(name string, data []byte) error
+	// CopyFile adds a file with the content of srcPath
+	CopyFile(name, srcPath string) error
+	// Close finishes the archive
+	Close() error
+}
+
+// tarArchive writes an archive as a tar stream
+type tarArchive struct {
+	tw   *tar.Writer
+	dirs map[string]bool
+}
+
+// NewTarArchive returns an ArchiveWriter that writes a tar stream to w
+// Entries get a fixed modification time so the same images always produce the same tar.
+func NewTarArchive(w io.Writer) ArchiveWriter {
+	return &tarArchive{tw: tar.NewWriter(w), dirs: make(map[string]bool)}
+}
+
+func (a *tarArchive) WriteFile(name string, data []byte) error {
+	if err := a.writeHeader(name, int64(len(data))); err != nil {
+		return err
+	}
+	if _, err := a.tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+func (a *tarArchive) CopyFile(name, srcPath string) error {
+	file, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := a.writeHeader(name, info.Size()); err != nil {
+		return err
+	}
+	if _, err := io.Copy(a.tw, file); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+func (a *tarArchive) Close() error {
+	return a.tw.Close()
+}
+
+// writeHeader writes the header of a regular file, adding entries for its parent directories first
+func (a *tarArchive) writeHeader(name string, size int64) error {
+	modTime := time.Unix(0, 0)
+
+	var parents []string
+	for dir := filepath.ToSlash(filepath.Dir(name)); dir != "." && !a.dirs[dir]; dir = filepath.ToSlash(filepath.Dir(dir)) {
+		parents = append([]string{dir}, parents...)
+	}
+	for _, dir := range parents {
+		header := &tar.Header{
+			Typeflag: tar.TypeDir,
+			Name:     dir + "/",
+			Mode:     0755,
+			ModTime:  modTime,
+		}
+		if err := a.tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write %s: %w", dir, err)
+		}
+		a.dirs[dir] = true
+	}
+
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644,
+		Size:     size,
+		ModTime:  modTime,
+	}
+	if err := a.tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// dirArchive writes an archive as files in a directory
+type dirArchive struct {
+	root string
+}
+
+// NewDirArchive returns an ArchiveWriter that writes files below dir, creating it if needed
+func NewDirArchive(dir string) (ArchiveWriter, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	return &dirArchive{root: dir}, nil
+}
+
+func (a *dirArchive) WriteFile(name string, data []byte) error {
+	path := filepath.Join(a.root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	return writeFileAtomic(path, data)
+}
+
+func (a *dirArchive) CopyFile(name, srcPath string) error {
+	path := filepath.Join(a.root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := copyFile(srcPath, path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+func (a *dirArchive) Close() error {
+	return nil
+}
+
+// extractArchive extracts a tar file, optionally gzip-compressed, into dir
+// Entries and link targets that would end up outside dir are rejected.
+func extractArchive(archivePath, dir string) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// docker save | gzip is common, so accept compressed tarballs too
+	buffered := bufio.NewReader(file)
+	var reader io.Reader = buffered
+	if magic, err := buffered.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(buffered)
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		target := archiveFilePath(dir, header.Name)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+
+		// A later entry replaces an earlier one; writing through an existing hard link
+		// would change the file it links to as well
+		if header.Typeflag != tar.TypeDir {
+			if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to extract %s: %w", header.Name, err)
+			}
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(out, tr)
+			if closeErr := out.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return fmt.Errorf("failed to extract %s: %w", header.Name, err)
+			}
+		case tar.TypeSymlink, tar.TypeLink:
+			// Older docker save archives link layers shared between images
+			linkName := header.Linkname
+			if header.Typeflag == tar.TypeSymlink {
+				linkName = path.Join(path.Dir(header.Name), linkName)
+			}
+			source := archiveFilePath(dir, linkName)
+			if err := os.Link(source, target); err != nil {
+				if err := copyFile(source, target); err != nil {
+					return fmt.Errorf("failed to extract %s: %w", header.Name, err)
+				}
+			}
+		}
+	}
+}
+
+// archiveFilePath resolves a slash-separated name from an archive below dir,
+// never leaving it
+func archiveFilePath(dir, name string) string {
+	return filepath.Join(dir, filepath.FromSlash(path.Clean("/"+name)))
+}
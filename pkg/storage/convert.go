@@ -0,0 +1,280 @@
+package storage
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ioworker0/timage/pkg/registry"
+	"github.com/klauspost/compress/zstd"
+)
+
+// attestationAnnotation marks the provenance/SBOM manifests BuildKit adds to an index
+const attestationAnnotation = "vnd.docker.reference.type"
+
+// ConvertImage converts a stored image to the Docker or OCI format and stores it as target
+// Manifest, config and layer media types are rewritten for the target format. Configs and
+// layers keep their content and digest unless the target format has no media type for
+// them: zstd layers are recompressed with gzip for Docker, which gives them a new digest
+// and size. An index is converted along with each child manifest; attestation manifests
+// are dropped when converting to a Docker manifest list, which can't describe them.
+// It returns the digest of the new manifest.
+func (s *Store) ConvertImage(source, target, format string) (string, error) {
+	data, err := s.LoadManifestRaw(source)
+	if err != nil {
+		return "", err
+	}
+
+	var converted []byte
+	if registry.IsManifestList(registry.DetectMediaType(data, "")) {
+		converted, err = s.convertIndex(data, format)
+	} else {
+		converted, err = s.convertManifest(data, format)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.SaveManifestRaw(target, converted); err != nil {
+		return "", err
+	}
+
+	return registry.ComputeDigest(converted), nil
+}
+
+// convertIndex converts a manifest list or index and stores its converted child manifests
+func (s *Store) convertIndex(data []byte, format string) ([]byte, error) {
+	index, err := registry.ParseOCIIndex(data)
+	if err != nil {
+		return nil, err
+	}
+
+	out := registry.OCIIndex{
+		SchemaVersion: 2,
+		MediaType:     registry.IndexMediaType(format),
+		Manifests:     []registry.Descriptor{},
+	}
+	if format == registry.FormatOCI {
+		out.ArtifactType = index.ArtifactType
+		out.Subject = index.Subject
+		out.Annotations = index.Annotations
+	}
+
+	for _, entry := range index.Manifests {
+		if format == registry.FormatDocker && entry.Annotations[attestationAnnotation] == "attestation-manifest" {
+			continue
+		}
+
+		child, err := s.LoadChildManifestRaw(entry.Digest)
+		if err != nil {
+			return nil, err
+		}
+		if registry.IsManifestList(registry.DetectMediaType(child, entry.MediaType)) {
+			return nil, fmt.Errorf("nested manifest lists are not supported")
+		}
+
+		convertedChild, err := s.convertManifest(child, format)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert manifest %s: %w", entry.Digest, err)
+		}
+
+		digest := registry.ComputeDigest(convertedChild)
+		if err := s.SaveChildManifest(digest, convertedChild); err != nil {
+			return nil, err
+		}
+
+		entry.MediaType = registry.ManifestMediaType(format)
+		entry.Digest = digest
+		entry.Size = int64(len(convertedChild))
+		out.Manifests = append(out.Manifests, entry)
+	}
+
+	return json.MarshalIndent(out, "", "  ")
+}
+
+// convertManifest converts an image manifest, recompressing layers where needed
+func (s *Store) convertManifest(data []byte, format string) ([]byte, error) {
+	manifest, err := registry.ParseOCIManifest(data)
+	if err != nil {
+		return nil, err
+	}
+	if !manifest.IsImage() {
+		return nil, fmt.Errorf("not a container image (config media type %q)", manifest.Config.MediaType)
+	}
+
+	out := registry.OCIManifest{
+		SchemaVersion: 2,
+		MediaType:     registry.ManifestMediaType(format),
+		Config:        manifest.Config,
+		Layers:        make([]registry.Descriptor, 0, len(manifest.Layers)),
+	}
+	out.Config.MediaType = registry.ConfigMediaType(format)
+	if format == registry.FormatOCI {
+		out.Subject = manifest.Subject
+		out.Annotations = manifest.Annotations
+	}
+
+	for _, layer := range manifest.Layers {
+		info, err := registry.GetLayerInfo(layer.MediaType)
+		if err != nil {
+			return nil, err
+		}
+
+		mediaType, ok := registry.LayerMediaType(format, info)
+		if !ok {
+			// No equivalent media type in the target format, store a gzip copy instead
+			layer, err = s.recompressLayer(layer, info.Compression)
+			if err != nil {
+				return nil, err
+			}
+			info.Compression = registry.CompressionGzip
+			if mediaType, ok = registry.LayerMediaType(format, info); !ok {
+				return nil, fmt.Errorf("layer %s can't be converted to %s", layer.Digest, format)
+			}
+		}
+
+		layer.MediaType = mediaType
+		out.Layers = append(out.Layers, layer)
+	}
+
+	return json.MarshalIndent(out, "", "  ")
+}
+
+// recompressLayer stores a gzip-compressed copy of a layer and returns its descriptor
+func (s *Store) recompressLayer(layer registry.Descriptor, compression string) (registry.Descriptor, error) {
+	reader, err := s.openUncompressedLayer(layer.Digest, compression)
+	if err != nil {
+		return layer, err
+	}
+	defer reader.Close()
+
+	digest, size, _, err := s.importGzipLayer(reader, "")
+	if err != nil {
+		return layer, fmt.Errorf("failed to recompress layer %s: %w", layer.Digest, err)
+	}
+
+	layer.Digest = digest
+	layer.Size = size
+	return layer, nil
+}
+
+// importGzipLayer gzip-compresses an uncompressed layer into the blob store
+// It returns the digest and size of the stored blob and the digest of the
+// uncompressed content, the layer's diff ID. If expectedDiffID is set, a layer
+// with a different diff ID is not stored and an *ErrDigestMismatch is returned.
+func (s *Store) importGzipLayer(reader io.Reader, expectedDiffID string) (digest string, size int64, diffID string, err error) {
+	tmpFile, err := os.CreateTemp("", TempFilePattern)
+	if err != nil {
+		return "", 0, "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+
+	// Compress into the temp file, hashing both sides as we go
+	compressedHasher := sha256.New()
+	uncompressedHasher := sha256.New()
+	counter := &countingWriter{writer: io.MultiWriter(tmpFile, compressedHasher)}
+	gzipWriter := gzip.NewWriter(counter)
+	_, err = io.Copy(io.MultiWriter(gzipWriter, uncompressedHasher), reader)
+	if closeErr := gzipWriter.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return "", 0, "", err
+	}
+
+	digest = fmt.Sprintf("sha256:%x", compressedHasher.Sum(nil))
+	diffID = fmt.Sprintf("sha256:%x", uncompressedHasher.Sum(nil))
+	if expectedDiffID != "" && diffID != expectedDiffID {
+		os.Remove(tmpPath)
+		return "", 0, "", &registry.ErrDigestMismatch{Expected: expectedDiffID, Actual: diffID}
+	}
+	if err := s.ImportBlob(digest, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return "", 0, "", err
+	}
+
+	return digest, counter.written, diffID, nil
+}
+
+// openUncompressedLayer opens a stored layer and returns a reader for its uncompressed tar
+func (s *Store) openUncompressedLayer(digest, compression string) (io.ReadCloser, error) {
+	blobPath, err := s.GetBlobPath(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(blobPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open layer %s: %w", digest, err)
+	}
+
+	reader, err := DecompressLayer(file, compression)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read layer %s: %w", digest, err)
+	}
+
+	return &layerReader{Reader: reader, closers: []func(){func() { reader.Close() }, func() { file.Close() }}}, nil
+}
+
+// DecompressLayer returns a reader for the uncompressed tar of a layer stream
+// Closing it releases the decompressor but leaves r open.
+func DecompressLayer(r io.Reader, compression string) (io.ReadCloser, error) {
+	switch compression {
+	case registry.CompressionNone:
+		return io.NopCloser(r), nil
+	case registry.CompressionGzip:
+		return gzip.NewReader(r)
+	case registry.CompressionZstd:
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	}
+
+	return nil, fmt.Errorf("unknown layer compression %q", compression)
+}
+
+// VerifyLayerDiffID decompresses a layer stream and checks the result against the layer's diff ID
+func VerifyLayerDiffID(r io.Reader, compression, diffID string) error {
+	reader, err := DecompressLayer(r, compression)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	return registry.VerifyReader(diffID, reader)
+}
+
+// layerReader reads a decompressed layer and releases the decompressor and file on Close
+type layerReader struct {
+	io.Reader
+	closers []func()
+}
+
+func (lr *layerReader) Close() error {
+	for _, closer := range lr.closers {
+		closer()
+	}
+	return nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	writer  io.Writer
+	written int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.writer.Write(p)
+	cw.written += int64(n)
+	return n, err
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"testing"
+
+	"github.com/ioworker0/timage/pkg/registry"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestVerifyLayerDiffID(t *testing.T) {
+	tar := []byte("layer content")
+	diffID := registry.ComputeDigest(tar)
+
+	var gzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	gzipWriter.Write(tar)
+	gzipWriter.Close()
+
+	var zstded bytes.Buffer
+	zstdWriter, err := zstd.NewWriter(&zstded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zstdWriter.Write(tar)
+	zstdWriter.Close()
+
+	tests := []struct {
+		name        string
+		data        []byte
+		compression string
+		diffID      string
+		mismatch    bool
+		wantErr     bool
+	}{
+		{name: "uncompressed", data: tar, compression: registry.CompressionNone, diffID: diffID},
+		{name: "gzip", data: gzipped.Bytes(), compression: registry.CompressionGzip, diffID: diffID},
+		{name: "zstd", data: zstded.Bytes(), compression: registry.CompressionZstd, diffID: diffID},
+		{name: "diff id of other content", data: gzipped.Bytes(), compression: registry.CompressionGzip,
+			diffID: registry.ComputeDigest([]byte("other")), mismatch: true},
+		{name: "compressed digest is not the diff id", data: gzipped.Bytes(), compression: registry.CompressionGzip,
+			diffID: registry.ComputeDigest(gzipped.Bytes()), mismatch: true},
+		{name: "not gzip", data: tar, compression: registry.CompressionGzip, diffID: diffID, wantErr: true},
+		{name: "unknown compression", data: tar, compression: "lz4", diffID: diffID, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		err := VerifyLayerDiffID(bytes.NewReader(tt.data), tt.compression, tt.diffID)
+
+		var mismatch *registry.ErrDigestMismatch
+		switch {
+		case tt.mismatch:
+			if !errors.As(err, &mismatch) {
+				t.Errorf("%s: VerifyLayerDiffID error = %v, want a digest mismatch", tt.name, err)
+			}
+		case tt.wantErr:
+			if err == nil || errors.As(err, &mismatch) {
+				t.Errorf("%s: VerifyLayerDiffID error = %v, want a read error", tt.name, err)
+			}
+		case err != nil:
+			t.Errorf("%s: VerifyLayerDiffID failed: %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/ioworker0/timage/pkg/registry"
+)
+
+// DockerManifestFile is the index of a docker save archive
+const DockerManifestFile = "manifest.json"
+
+// DockerArchiveEntry is an image in the manifest.json of a docker save archive
+type DockerArchiveEntry struct {
+	Config   string   `json:"Config"`
+	RepoTags []string `json:"RepoTags"`
+	Layers   []string `json:"Layers"`
+}
+
+// ExportDockerArchive writes stored images in the layout of docker save, which docker load reads
+// Each image is written as its config, <hex>.json, and one <diff id>/layer.tar per
+// layer with the layer decompressed. Images are tagged with their local names, as repo:tag, in
+// RepoTags. docker load has no notion of manifest lists, so for an index the image
+// for platform is exported.
+func (s *Store) ExportDockerArchive(out ArchiveWriter, images []string, platform registry.Platform) error {
+	var entries []*DockerArchiveEntry
+	byConfig := make(map[string]*DockerArchiveEntry)
+	written := make(map[string]bool)
+
+	for _, image := range images {
+		data, err := s.LoadPlatformManifestRaw(image, platform)
+		if err != nil {
+			return err
+		}
+
+		manifest, err := registry.ParseOCIManifest(data)
+		if err != nil {
+			return fmt.Errorf("failed to export %s: %w", image, err)
+		}
+		if !manifest.IsImage() {
+			return fmt.Errorf("failed to export %s: not a container image", image)
+		}
+
+		// Images sharing a config are the same image under several tags
+		entry, ok := byConfig[manifest.Config.Digest]
+		if !ok {
+			entry, err = s.exportDockerImage(out, manifest, written)
+			if err != nil {
+				return fmt.Errorf("failed to export %s: %w", image, err)
+			}
+			byConfig[manifest.Config.Digest] = entry
+			entries = append(entries, entry)
+		}
+
+		if repoTag, ok := dockerRepoTag(image); ok {
+			entry.RepoTags = append(entry.RepoTags, repoTag)
+		}
+	}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	return out.WriteFile(DockerManifestFile, data)
+}
+
+// dockerRepoTag returns the repo:tag docker load registers an image under
+// A missing tag defaults to latest. Images referenced by digest have no tag to restore.
+func dockerRepoTag(image string) (string, bool) {
+	if strings.Contains(image, "@") {
+		return "", false
+	}
+	if !strings.Contains(image[strings.LastIndex(image, "/")+1:], ":") {
+		image += ":latest"
+	}
+	return image, true
+}
+
+// exportDockerImage writes the config and decompressed layers of an image
+func (s *Store) exportDockerImage(out ArchiveWriter, manifest *registry.OCIManifest, written map[string]bool) (*DockerArchiveEntry, error) {
+	config, err := s.LoadBlob(manifest.Config.Digest)
+	if err != nil {
+		return nil, err
+	}
+
+	imageConfig, err := registry.ParseImageConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := imageConfig.ValidateDiffIDs(manifest.Layers); err != nil {
+		return nil, err
+	}
+	diffIDs := imageConfig.RootFS.DiffIDs
+
+	_, configHex, err := splitDigest(manifest.Config.Digest)
+	if err != nil {
+		return nil, err
+	}
+	entry := &DockerArchiveEntry{Config: configHex + ".json", RepoTags: []string{}}
+	if !written[manifest.Config.Digest] {
+		if err := out.WriteFile(entry.Config, config); err != nil {
+			return nil, err
+		}
+		written[manifest.Config.Digest] = true
+	}
+
+	for i, layer := range manifest.Layers {
+		_, diffHex, err := splitDigest(diffIDs[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid diff id: %w", err)
+		}
+
+		name := path.Join(diffHex, "layer.tar")
+		entry.Layers = append(entry.Layers, name)
+		if written[diffIDs[i]] {
+			continue
+		}
+
+		if err := s.exportUncompressedLayer(out, name, layer, diffIDs[i]); err != nil {
+			return nil, err
+		}
+		written[diffIDs[i]] = true
+	}
+
+	return entry, nil
+}
+
+// exportUncompressedLayer writes a layer as an uncompressed tar, checking it against its diff id
+// The layer is decompressed to a temp file first since the archive needs its size up front.
+func (s *Store) exportUncompressedLayer(out ArchiveWriter, name string, layer registry.Descriptor, diffID string) error {
+	info, err := registry.GetLayerInfo(layer.MediaType)
+	if err != nil {
+		return err
+	}
+	if !s.HasBlob(layer.Digest) {
+		return fmt.Errorf("blob %s is missing from the local store", layer.Digest)
+	}
+
+	reader, err := s.openUncompressedLayer(layer.Digest, info.Compression)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	tmpFile, err := os.CreateTemp("", TempFilePattern)
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+
+	hasher := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmpFile, hasher), reader)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decompress layer %s: %w", layer.Digest, err)
+	}
+
+	if actual := fmt.Sprintf("sha256:%x", hasher.Sum(nil)); actual != diffID {
+		return fmt.Errorf("layer %s: %w", layer.Digest, &registry.ErrDigestMismatch{Expected: diffID, Actual: actual})
+	}
+
+	return out.CopyFile(name, tmpPath)
+}
@@ -0,0 +1,300 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// TempFilePattern matches the temporary files written while downloading blobs
+const TempFilePattern = "timage-*.tmp"
+
+// GCOptions controls a garbage collection run
+type GCOptions struct {
+	// DryRun reports what would be removed without removing anything
+	DryRun bool
+	// MinAge protects files modified more recently than this, so data
+	// written by a process that doesn't hold LockForWrite is not collected
+	MinAge time.Duration
+}
+
+// GCResult reports what a garbage collection run removed
+type GCResult struct {
+	RemovedBlobs     []string
+	RemovedTempFiles []string
+	RemovedTempDirs  []string
+	RemovedImageDirs []string
+	FreedBytes       int64
+}
+
+// ErrStoreBusy is returned by GarbageCollect while another process writes to the store
+var ErrStoreBusy = errors.New("another timage process is adding images to the store; run gc again once it has finished")
+
+// GarbageCollect removes blobs no manifest refers to, leftover temporary
+// download files and extraction directories, and image directories without
+// a readable manifest
+// It fails with ErrStoreBusy while another process holds LockForWrite.
+func (s *Store) GarbageCollect(opts GCOptions) (*GCResult, error) {
+	// A pull stores its blobs long before the manifest that refers to them
+	lock, err := acquireLock(s.layout.GetLockPath(), true, false)
+	if errors.Is(err, errLocked) {
+		return nil, ErrStoreBusy
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	result := &GCResult{}
+	cutoff := time.Now().Add(-opts.MinAge)
+
+	// Mark: collect every digest referenced by a stored manifest,
+	// removing half-written image directories along the way
+	referenced, err := s.markReferenced(opts, cutoff, result)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sweep: remove unreferenced blobs and temp files in the blob store
+	if err := s.sweepBlobs(referenced, opts, cutoff, result); err != nil {
+		return nil, err
+	}
+
+	// Remove leftover download files, including partial downloads kept for resuming
+	matches, err := filepath.Glob(filepath.Join(os.TempDir(), TempFilePattern))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list temp files: %w", err)
+	}
+	partials, err := filepath.Glob(filepath.Join(s.layout.GetDownloadsDir(), "*.partial"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partial downloads: %w", err)
+	}
+	matches = append(matches, partials...)
+	for _, path := range matches {
+		size, ok := removeIfOld(path, opts, cutoff)
+		if ok {
+			result.RemovedTempFiles = append(result.RemovedTempFiles, path)
+			result.FreedBytes += size
+		}
+	}
+
+	// Remove archives extracted by a load that was interrupted
+	loadDirs, err := filepath.Glob(filepath.Join(s.layout.GetDownloadsDir(), LoadDirPattern))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list load directories: %w", err)
+	}
+	for _, path := range loadDirs {
+		size, ok, err := removeDirIfOld(path, opts, cutoff)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result.RemovedTempDirs = append(result.RemovedTempDirs, path)
+			result.FreedBytes += size
+		}
+	}
+
+	return result, nil
+}
+
+// markReferenced returns the set of digests referenced by stored manifests
+func (s *Store) markReferenced(opts GCOptions, cutoff time.Time, result *GCResult) (map[string]bool, error) {
+	referenced := make(map[string]bool)
+	imagesDir := filepath.Join(s.layout.rootDir, "images")
+
+	entries, err := os.ReadDir(imagesDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return referenced, nil
+		}
+		return nil, fmt.Errorf("failed to read images directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		imageDir := filepath.Join(imagesDir, entry.Name())
+		data, err := os.ReadFile(filepath.Join(imageDir, "manifest.json"))
+		if err == nil {
+			digests, err := s.imageReferences(data)
+			if err == nil {
+				for _, digest := range digests {
+					referenced[digest] = true
+				}
+				continue
+			}
+		}
+
+		// No readable manifest: the directory was left behind by an interrupted pull
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+
+		size := dirSize(imageDir)
+		if !opts.DryRun {
+			if err := os.RemoveAll(imageDir); err != nil {
+				return nil, fmt.Errorf("failed to remove %s: %w", imageDir, err)
+			}
+		}
+		result.RemovedImageDirs = append(result.RemovedImageDirs, imageDir)
+		result.FreedBytes += size
+	}
+
+	return referenced, nil
+}
+
+// sweepBlobs removes every blob that is not in the referenced set
+func (s *Store) sweepBlobs(referenced map[string]bool, opts GCOptions, cutoff time.Time, result *GCResult) error {
+	blobsDir := s.layout.GetBlobsDir()
+
+	algorithms, err := os.ReadDir(blobsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read blobs directory: %w", err)
+	}
+
+	for _, algorithm := range algorithms {
+		if !algorithm.IsDir() {
+			continue
+		}
+
+		algorithmDir := filepath.Join(blobsDir, algorithm.Name())
+		blobs, err := os.ReadDir(algorithmDir)
+		if err != nil {
+			return fmt.Errorf("failed to read blobs directory: %w", err)
+		}
+
+		for _, blob := range blobs {
+			if blob.IsDir() {
+				continue
+			}
+
+			path := filepath.Join(algorithmDir, blob.Name())
+
+			// Temp files from interrupted writes into the store
+			if strings.HasPrefix(blob.Name(), ".tmp-") {
+				if size, ok := removeIfOld(path, opts, cutoff); ok {
+					result.RemovedTempFiles = append(result.RemovedTempFiles, path)
+					result.FreedBytes += size
+				}
+				continue
+			}
+
+			digest := algorithm.Name() + ":" + blob.Name()
+			if referenced[digest] {
+				continue
+			}
+
+			if size, ok := removeIfOld(path, opts, cutoff); ok {
+				result.RemovedBlobs = append(result.RemovedBlobs, digest)
+				result.FreedBytes += size
+			}
+		}
+	}
+
+	return nil
+}
+
+// manifestReferences returns the digests of all blobs and manifests a manifest refers to
+func manifestReferences(data []byte) ([]string, error) {
+	var manifest struct {
+		Config struct {
+			Digest string `json:"digest"`
+		} `json:"config"`
+		Layers []struct {
+			Digest string `json:"digest"`
+		} `json:"layers"`
+		Manifests []struct {
+			Digest string `json:"digest"`
+		} `json:"manifests"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+
+	var digests []string
+	if manifest.Config.Digest != "" {
+		digests = append(digests, manifest.Config.Digest)
+	}
+	for _, layer := range manifest.Layers {
+		digests = append(digests, layer.Digest)
+	}
+	for _, m := range manifest.Manifests {
+		digests = append(digests, m.Digest)
+	}
+
+	return digests, nil
+}
+
+// removeIfOld removes a file unless it was modified after cutoff
+// It returns the file size and whether the file was (or in a dry run would be) removed.
+func removeIfOld(path string, opts GCOptions, cutoff time.Time) (int64, bool) {
+	info, err := os.Stat(path)
+	if err != nil || info.ModTime().After(cutoff) {
+		return 0, false
+	}
+
+	if !opts.DryRun {
+		if err := os.Remove(path); err != nil {
+			return 0, false
+		}
+	}
+
+	return info.Size(), true
+}
+
+// removeDirIfOld removes a directory tree unless anything in it was modified after cutoff
+// It returns the size of the files in it and whether it was (or in a dry run would be) removed.
+func removeDirIfOld(dir string, opts GCOptions, cutoff time.Time) (int64, bool, error) {
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return 0, false, nil
+	}
+
+	var size int64
+	recent := false
+	filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.ModTime().After(cutoff) {
+			recent = true
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	if recent {
+		return 0, false, nil
+	}
+
+	if !opts.DryRun {
+		if err := os.RemoveAll(dir); err != nil {
+			return 0, false, fmt.Errorf("failed to remove %s: %w", dir, err)
+		}
+	}
+
+	return size, true, nil
+}
+
+// dirSize returns the total size of the files in a directory tree
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ioworker0/timage/pkg/registry"
+)
+
+// ImageInfo describes a stored image
+type ImageInfo struct {
+	Name       string
+	Repository string
+	// Tag is empty for images stored by digest
+	Tag       string
+	Digest    string
+	MediaType string
+	// Created, Platform and Labels come from the image config; for an index they
+	// describe the image for the host platform, or else the first stored child
+	Created  *time.Time        `json:",omitempty"`
+	Platform string            `json:",omitempty"`
+	Labels   map[string]string `json:",omitempty"`
+	// Size is the disk space used by the manifest and every blob it refers to,
+	// including blobs shared with other images
+	Size int64
+}
+
+// Dangling reports whether an image has no tag
+func (i *ImageInfo) Dangling() bool {
+	return i.Tag == ""
+}
+
+// ListImageInfo returns the details of every stored image
+// Images whose manifest can't be read are listed by name only, with the rest empty.
+func (s *Store) ListImageInfo() ([]ImageInfo, error) {
+	images, err := s.ListImages()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]ImageInfo, 0, len(images))
+	for _, image := range images {
+		info, err := s.GetImageInfo(image)
+		if err != nil {
+			info = &ImageInfo{Name: image}
+			info.Repository, info.Tag = splitImageName(image)
+		}
+		infos = append(infos, *info)
+	}
+
+	return infos, nil
+}
+
+// GetImageInfo returns the details of a stored image
+func (s *Store) GetImageInfo(imageName string) (*ImageInfo, error) {
+	data, err := s.LoadManifestRaw(imageName)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &ImageInfo{
+		Name:      imageName,
+		Digest:    registry.ComputeDigest(data),
+		MediaType: registry.DetectMediaType(data, ""),
+		Size:      int64(len(data)),
+	}
+	info.Repository, info.Tag = splitImageName(imageName)
+
+	// Add up every blob once; the same layer can appear in several children
+	if digests, err := s.imageReferences(data); err == nil {
+		seen := make(map[string]bool)
+		for _, digest := range digests {
+			if seen[digest] {
+				continue
+			}
+			seen[digest] = true
+
+			blobPath, err := s.GetBlobPath(digest)
+			if err != nil {
+				continue
+			}
+			if stat, err := os.Stat(blobPath); err == nil {
+				info.Size += stat.Size()
+			}
+		}
+	}
+
+	if config, err := s.loadInfoConfig(imageName, data, info.MediaType); err == nil {
+		info.Created = config.Created
+		info.Platform = config.Platform().String()
+		info.Labels = config.Config.Labels
+	}
+
+	return info, nil
+}
+
+// loadInfoConfig loads the config that Created, Platform and Labels are read from
+func (s *Store) loadInfoConfig(imageName string, data []byte, mediaType string) (*registry.ImageConfig, error) {
+	config, err := s.LoadImageConfig(imageName, registry.DefaultPlatform())
+	if err == nil || !registry.IsManifestList(mediaType) {
+		return config, err
+	}
+
+	// The index has no image for the host platform; describe the first one stored
+	child, err := s.firstChildManifest(data)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := registry.ParseOCIManifest(child)
+	if err != nil {
+		return nil, err
+	}
+
+	configData, err := s.LoadBlob(manifest.Config.Digest)
+	if err != nil {
+		return nil, err
+	}
+
+	return registry.ParseImageConfig(configData)
+}
+
+// firstChildManifest returns the first child manifest of an index that is stored
+func (s *Store) firstChildManifest(indexData []byte) ([]byte, error) {
+	var index registry.Manifest
+	if err := json.Unmarshal(indexData, &index); err != nil {
+		return nil, err
+	}
+
+	var lastErr error = os.ErrNotExist
+	for _, entry := range index.Manifests {
+		data, err := s.LoadChildManifestRaw(entry.Digest)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return data, nil
+	}
+
+	return nil, lastErr
+}
+
+// splitImageName splits an image name into repository and tag
+// The tag is empty for a name@digest reference, and latest when a name has neither.
+func splitImageName(imageName string) (repository, tag string) {
+	if idx := strings.Index(imageName, "@"); idx != -1 {
+		return imageName[:idx], ""
+	}
+
+	// A colon before the last slash belongs to a registry port
+	if idx := strings.LastIndex(imageName, ":"); idx > strings.LastIndex(imageName, "/") {
+		return imageName[:idx], imageName[idx+1:]
+	}
+
+	return imageName, "latest"
+}
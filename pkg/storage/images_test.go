@@ -0,0 +1,26 @@
+package storage
+
+import "testing"
+
+func TestSplitImageName(t *testing.T) {
+	tests := []struct {
+		name       string
+		repository string
+		tag        string
+	}{
+		{"docker.io/library/nginx:1.27", "docker.io/library/nginx", "1.27"},
+		{"docker.io/library/nginx", "docker.io/library/nginx", "latest"},
+		{"nginx:alpine", "nginx", "alpine"},
+		{"localhost:5000/app", "localhost:5000/app", "latest"},
+		{"localhost:5000/app:v2", "localhost:5000/app", "v2"},
+		{"ghcr.io/org/app@sha256:abc", "ghcr.io/org/app", ""},
+		{"localhost:5000/app@sha256:abc", "localhost:5000/app", ""},
+	}
+
+	for _, tt := range tests {
+		repository, tag := splitImageName(tt.name)
+		if repository != tt.repository || tag != tt.tag {
+			t.Errorf("splitImageName(%q) = %q, %q, want %q, %q", tt.name, repository, tag, tt.repository, tt.tag)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ioworker0/timage/pkg/registry"
+)
+
+// A stored image is either a single manifest, or a manifest list / OCI image index.
+// For an index, images/<name>/manifest.json holds the index exactly as pulled and
+// every child manifest is kept in the blob store under its own digest, next to
+// the configs and layers it references.
+
+// IsIndex reports whether a stored image is a manifest list or OCI image index
+func (s *Store) IsIndex(imageName string) (bool, error) {
+	data, err := s.LoadManifestRaw(imageName)
+	if err != nil {
+		return false, err
+	}
+
+	return registry.IsManifestList(registry.DetectMediaType(data, "")), nil
+}
+
+// SaveChildManifest stores a child manifest of an index in the blob store
+// The data must match the digest the index refers to it by.
+func (s *Store) SaveChildManifest(digest string, data []byte) error {
+	if err := registry.VerifyDigest(digest, data); err != nil {
+		return fmt.Errorf("invalid child manifest: %w", err)
+	}
+
+	return s.SaveBlob(digest, data)
+}
+
+// LoadChildManifestRaw loads the raw bytes of a child manifest of an index
+func (s *Store) LoadChildManifestRaw(digest string) ([]byte, error) {
+	data, err := s.LoadBlob(digest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read child manifest %s: %w", digest, err)
+	}
+
+	return data, nil
+}
+
+// LoadPlatformManifestRaw loads the raw manifest of an image for a platform
+// For a single-manifest image the manifest is returned as is.
+func (s *Store) LoadPlatformManifestRaw(imageName string, platform registry.Platform) ([]byte, error) {
+	data, err := s.LoadManifestRaw(imageName)
+	if err != nil {
+		return nil, err
+	}
+
+	if !registry.IsManifestList(registry.DetectMediaType(data, "")) {
+		return data, nil
+	}
+
+	var index registry.Manifest
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal index: %w", err)
+	}
+
+	entry, err := registry.SelectManifest(index.Manifests, platform)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.LoadChildManifestRaw(entry.Digest)
+}
+
+// LoadPlatformManifest loads the manifest of an image for a platform
+func (s *Store) LoadPlatformManifest(imageName string, platform registry.Platform) (*registry.Manifest, error) {
+	data, err := s.LoadPlatformManifestRaw(imageName, platform)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest registry.Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal manifest: %w", err)
+	}
+
+	return &manifest, nil
+}
+
+// imageReferences returns every digest a stored manifest refers to, following
+// the child manifests of an index into the blob store
+func (s *Store) imageReferences(data []byte) ([]string, error) {
+	digests, err := manifestReferences(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if !registry.IsManifestList(registry.DetectMediaType(data, "")) {
+		return digests, nil
+	}
+
+	var index registry.Manifest
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, err
+	}
+
+	for _, entry := range index.Manifests {
+		child, err := s.LoadBlob(entry.Digest)
+		if err != nil {
+			// Child not stored (e.g. a single-platform pull); nothing more to follow
+			continue
+		}
+
+		childDigests, err := manifestReferences(child)
+		if err != nil {
+			continue
+		}
+		digests = append(digests, childDigests...)
+	}
+
+	return digests, nil
+}
@@ -19,9 +19,16 @@ func NewLayout(rootDir string) (*Layout, error) {
 		return nil, fmt.Errorf("failed to create root directory: %w", err)
 	}
 
-	return &Layout{
+	layout := &Layout{
 		rootDir: rootDir,
-	}, nil
+	}
+
+	// Move images stored in the old per-image layout into the shared blob store
+	if err := layout.migrate(); err != nil {
+		return nil, fmt.Errorf("failed to migrate storage: %w", err)
+	}
+
+	return layout, nil
 }
 
 // GetImageDir returns the directory for a specific image
@@ -40,34 +47,122 @@ func (l *Layout) GetManifestPath(imageName string) string {
 	return filepath.Join(l.GetImageDir(imageName), "manifest.json")
 }
 
-// GetConfigPath returns the path to the config file
-func (l *Layout) GetConfigPath(imageName string) string {
-	return filepath.Join(l.GetImageDir(imageName), "config.json")
+// GetBlobsDir returns the root directory of the shared blob store
+func (l *Layout) GetBlobsDir() string {
+	return filepath.Join(l.rootDir, "blobs")
 }
 
-// GetLayersDir returns the directory for layers
-func (l *Layout) GetLayersDir(imageName string) string {
-	return filepath.Join(l.GetImageDir(imageName), "layers")
+// GetBlobPath returns the path to a blob in the shared blob store
+// Blobs are content addressed: blobs/<algorithm>/<hex>
+func (l *Layout) GetBlobPath(digest string) (string, error) {
+	algorithm, encoded, err := splitDigest(digest)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(l.GetBlobsDir(), algorithm, encoded), nil
 }
 
-// GetLayerPath returns the path to a specific layer
-func (l *Layout) GetLayerPath(imageName, digest string) string {
-	// Use digest as filename (with : replaced by _)
-	safeDigest := strings.ReplaceAll(digest, ":", "_")
-	return filepath.Join(l.GetLayersDir(imageName), safeDigest+".tar.gz")
+// GetLockPath returns the file writers and garbage collection lock the store with
+func (l *Layout) GetLockPath() string {
+	return filepath.Join(l.rootDir, "store.lock")
+}
+
+// GetDownloadsDir returns the directory holding partial downloads
+func (l *Layout) GetDownloadsDir() string {
+	return filepath.Join(l.rootDir, "downloads")
+}
+
+// GetPartialPath returns the path of a partial download for a blob
+// The path only depends on the digest so an interrupted download can be
+// found and resumed by a later process.
+func (l *Layout) GetPartialPath(digest string) (string, error) {
+	algorithm, encoded, err := splitDigest(digest)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(l.GetDownloadsDir(), algorithm+"-"+encoded+".partial"), nil
 }
 
 // CreateImageDir creates the directory structure for an image
 func (l *Layout) CreateImageDir(imageName string) error {
-	layersDir := l.GetLayersDir(imageName)
-
-	if err := os.MkdirAll(layersDir, 0755); err != nil {
+	if err := os.MkdirAll(l.GetImageDir(imageName), 0755); err != nil {
 		return fmt.Errorf("failed to create image directory: %w", err)
 	}
 
 	return nil
 }
 
+// importBlob moves a file into the shared blob store under the given digest
+// The source file is consumed. If the blob already exists the source is discarded.
+func (l *Layout) importBlob(digest, srcPath string) error {
+	blobPath, err := l.GetBlobPath(digest)
+	if err != nil {
+		return err
+	}
+
+	// Blob already stored, nothing to do
+	if _, err := os.Stat(blobPath); err == nil {
+		return os.Remove(srcPath)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(blobPath), 0755); err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	// Rename is atomic and cheap when source and store are on the same filesystem
+	if err := os.Rename(srcPath, blobPath); err == nil {
+		return os.Chmod(blobPath, 0644)
+	}
+
+	// Fall back to copying into a temp file next to the blob, then renaming it in place
+	tmpFile, err := os.CreateTemp(filepath.Dir(blobPath), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	tmpFile.Close()
+
+	if err := copyFile(srcPath, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to copy blob: %w", err)
+	}
+
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to store blob: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, blobPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to store blob: %w", err)
+	}
+
+	return os.Remove(srcPath)
+}
+
+// splitDigest splits a digest into algorithm and hex parts and validates them,
+// so a digest can never be used to escape the blob store
+func splitDigest(digest string) (algorithm, encoded string, err error) {
+	parts := strings.SplitN(digest, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid digest: %q", digest)
+	}
+
+	algorithm, encoded = parts[0], parts[1]
+	for _, r := range algorithm {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '+' || r == '.' || r == '_' || r == '-') {
+			return "", "", fmt.Errorf("invalid digest algorithm: %q", digest)
+		}
+	}
+	for _, r := range encoded {
+		if !(r >= 'a' && r <= 'f' || r >= '0' && r <= '9') {
+			return "", "", fmt.Errorf("invalid digest encoding: %q", digest)
+		}
+	}
+
+	return algorithm, encoded, nil
+}
+
 // decodeOldEncoding decodes old encoding format (where both / and : were replaced with _)
 // Uses heuristic: last _ is likely the tag separator (:), others are path separators (/)
 func decodeOldEncoding(dirName string) string {
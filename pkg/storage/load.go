@@ -0,0 +1,416 @@
+package storage
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/ioworker0/timage/pkg/registry"
+	"github.com/klauspost/compress/zstd"
+)
+
+// LoadDirPattern matches the directories archives are extracted into while loading
+const LoadDirPattern = "load-*"
+
+// LoadResult reports what an archive import stored
+type LoadResult struct {
+	// Images are the names registered for the imported images
+	Images []string
+	// Skipped identifies the images the archive doesn't name, which are only loaded
+	// when a repository is given for them: the manifest digest for an OCI image
+	// layout, the config file for a docker save archive
+	Skipped []string
+}
+
+// LoadArchive imports the images of an OCI image layout or a docker save archive
+// The source can be a tar file, optionally gzip-compressed, or an extracted directory.
+// Every blob is verified against its digest before it is stored. Layers of a docker
+// save archive are stored gzip-compressed under a Docker schema 2 manifest.
+// Images without a name in the archive are registered as repository@digest, or
+// skipped without importing anything when repository is empty. Images named only
+// by a tag are registered as repository:tag and need a repository.
+func (s *Store) LoadArchive(source, repository string) (*LoadResult, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := source
+	consume := false
+	if !info.IsDir() {
+		// Extract next to the blob store so blobs can be moved into it
+		if err := os.MkdirAll(s.layout.GetDownloadsDir(), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create downloads directory: %w", err)
+		}
+		dir, err = os.MkdirTemp(s.layout.GetDownloadsDir(), LoadDirPattern)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		defer os.RemoveAll(dir)
+
+		if err := extractArchive(source, dir); err != nil {
+			return nil, err
+		}
+		consume = true
+	}
+
+	loader := &archiveLoader{store: s, dir: dir, consume: consume, repository: repository, imported: make(map[string]bool)}
+
+	// Docker 25 and later write both layouts; the OCI one is complete
+	if _, err := os.Stat(archiveFilePath(dir, OCILayoutFile)); err == nil {
+		return loader.loadOCILayout()
+	}
+	if _, err := os.Stat(archiveFilePath(dir, DockerManifestFile)); err == nil {
+		return loader.loadDockerArchive()
+	}
+
+	return nil, fmt.Errorf("%s is neither an OCI image layout nor a docker save archive", source)
+}
+
+// archiveLoader imports the content of an extracted archive
+type archiveLoader struct {
+	store *Store
+	dir   string
+	// consume allows moving files out of dir instead of copying them
+	consume bool
+	// repository is where images without a name are registered
+	repository string
+	imported   map[string]bool
+}
+
+// loadOCILayout imports every image listed in index.json
+func (l *archiveLoader) loadOCILayout() (*LoadResult, error) {
+	data, err := os.ReadFile(archiveFilePath(l.dir, OCIIndexFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", OCIIndexFile, err)
+	}
+
+	index, err := registry.ParseOCIIndex(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Name every image first; unnamed ones are left out when there's nowhere to register them
+	result := &LoadResult{}
+	var entries []registry.Descriptor
+	var names []string
+	for _, entry := range index.Manifests {
+		name := entry.Annotations[AnnotationContainerdImageName]
+		if name == "" {
+			name = entry.Annotations[AnnotationRefName]
+
+			// skopeo and podman write just the tag; it needs a repository to go with it
+			if name != "" && !strings.ContainsAny(name, "/:@") {
+				if l.repository == "" {
+					return nil, fmt.Errorf("image %s is only tagged %q in the archive; use --repository to name its repository", entry.Digest, name)
+				}
+				name = l.repository + ":" + name
+			}
+		}
+		if name == "" {
+			if l.repository == "" {
+				result.Skipped = append(result.Skipped, entry.Digest)
+				continue
+			}
+			name = l.repository + "@" + entry.Digest
+		}
+		entries = append(entries, entry)
+		names = append(names, name)
+	}
+
+	// Import all content first so no manifest is stored before the data it refers to
+	manifests := make(map[string][]byte)
+	for _, entry := range entries {
+		if _, ok := manifests[entry.Digest]; ok {
+			continue
+		}
+		manifest, err := l.importOCIManifest(entry.Digest)
+		if err != nil {
+			return nil, err
+		}
+		manifests[entry.Digest] = manifest
+	}
+
+	for i, entry := range entries {
+		if err := l.store.SaveManifestRaw(names[i], manifests[entry.Digest]); err != nil {
+			return nil, err
+		}
+		result.Images = append(result.Images, names[i])
+	}
+
+	return result, nil
+}
+
+// importOCIManifest imports a manifest from the blobs directory along with everything
+// it refers to and returns its content
+// Child manifests of an index that are not in the layout are skipped, like a
+// single-platform pull leaves them out.
+func (l *archiveLoader) importOCIManifest(digest string) ([]byte, error) {
+	blobPath, err := l.blobPath(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(blobPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %w", digest, err)
+	}
+	if err := registry.VerifyDigest(digest, data); err != nil {
+		return nil, fmt.Errorf("manifest %s: %w", digest, err)
+	}
+
+	if registry.IsManifestList(registry.DetectMediaType(data, "")) {
+		index, err := registry.ParseOCIIndex(data)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range index.Manifests {
+			childPath, err := l.blobPath(entry.Digest)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := os.Stat(childPath); os.IsNotExist(err) {
+				continue
+			}
+
+			child, err := l.importOCIManifest(entry.Digest)
+			if err != nil {
+				return nil, err
+			}
+			if err := l.store.SaveChildManifest(entry.Digest, child); err != nil {
+				return nil, err
+			}
+		}
+		return data, nil
+	}
+
+	digests, err := manifestReferences(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", digest, err)
+	}
+	for _, blob := range digests {
+		if err := l.importBlob(blob); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := l.verifyDiffIDs(data); err != nil {
+		return nil, fmt.Errorf("manifest %s: %w", digest, err)
+	}
+
+	return data, nil
+}
+
+// verifyDiffIDs checks the imported layers of an image manifest against its config
+func (l *archiveLoader) verifyDiffIDs(data []byte) error {
+	manifest, err := registry.ParseOCIManifest(data)
+	if err != nil {
+		return err
+	}
+	if !manifest.IsImage() {
+		return nil
+	}
+
+	configData, err := l.store.LoadBlob(manifest.Config.Digest)
+	if err != nil {
+		return err
+	}
+	config, err := registry.ParseImageConfig(configData)
+	if err != nil {
+		return err
+	}
+
+	return l.store.VerifyDiffIDs(manifest, config)
+}
+
+// importBlob verifies a blob from the blobs directory and moves or copies it into the store
+func (l *archiveLoader) importBlob(digest string) error {
+	if l.imported[digest] || l.store.HasBlob(digest) {
+		l.imported[digest] = true
+		return nil
+	}
+
+	blobPath, err := l.blobPath(digest)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(blobPath)
+	if err != nil {
+		return fmt.Errorf("blob %s is missing from the archive", digest)
+	}
+	err = registry.VerifyReader(digest, file)
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("blob %s: %w", digest, err)
+	}
+
+	if !l.consume {
+		tmpFile, err := os.CreateTemp("", TempFilePattern)
+		if err != nil {
+			return fmt.Errorf("failed to create temp file: %w", err)
+		}
+		tmpPath := tmpFile.Name()
+		tmpFile.Close()
+		if err := copyFile(blobPath, tmpPath); err != nil {
+			os.Remove(tmpPath)
+			return fmt.Errorf("failed to copy blob %s: %w", digest, err)
+		}
+		blobPath = tmpPath
+	}
+
+	if err := l.store.ImportBlob(digest, blobPath); err != nil {
+		return err
+	}
+	l.imported[digest] = true
+	return nil
+}
+
+// blobPath returns the path of a blob in an OCI image layout
+func (l *archiveLoader) blobPath(digest string) (string, error) {
+	name, err := blobArchivePath(digest)
+	if err != nil {
+		return "", err
+	}
+	return archiveFilePath(l.dir, name), nil
+}
+
+// loadDockerArchive imports every image listed in the manifest.json of a docker save archive
+func (l *archiveLoader) loadDockerArchive() (*LoadResult, error) {
+	data, err := os.ReadFile(archiveFilePath(l.dir, DockerManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", DockerManifestFile, err)
+	}
+
+	var entries []DockerArchiveEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", DockerManifestFile, err)
+	}
+
+	result := &LoadResult{}
+	for _, entry := range entries {
+		if len(entry.RepoTags) == 0 && l.repository == "" {
+			result.Skipped = append(result.Skipped, entry.Config)
+			continue
+		}
+
+		manifest, err := l.importDockerImage(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		tags := entry.RepoTags
+		if len(tags) == 0 {
+			tags = []string{l.repository + "@" + registry.ComputeDigest(manifest)}
+		}
+		for _, tag := range tags {
+			if err := l.store.SaveManifestRaw(tag, manifest); err != nil {
+				return nil, err
+			}
+			result.Images = append(result.Images, tag)
+		}
+	}
+
+	return result, nil
+}
+
+// importDockerImage stores the config and layers of a docker save image and
+// returns a Docker schema 2 manifest for them
+func (l *archiveLoader) importDockerImage(entry DockerArchiveEntry) ([]byte, error) {
+	config, err := os.ReadFile(archiveFilePath(l.dir, entry.Config))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %w", entry.Config, err)
+	}
+
+	imageConfig, err := registry.ParseImageConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", entry.Config, err)
+	}
+	// Layers are only known by their path until they are imported, which is enough to
+	// check there's a diff ID for each
+	if err := imageConfig.ValidateDiffIDs(make([]registry.Descriptor, len(entry.Layers))); err != nil {
+		return nil, fmt.Errorf("config %s: %w", entry.Config, err)
+	}
+	diffIDs := imageConfig.RootFS.DiffIDs
+
+	configDigest := registry.ComputeDigest(config)
+	manifest := registry.OCIManifest{
+		SchemaVersion: 2,
+		MediaType:     registry.MediaTypeDockerManifest,
+		Config: registry.Descriptor{
+			MediaType: registry.MediaTypeDockerConfig,
+			Size:      int64(len(config)),
+			Digest:    configDigest,
+		},
+		Layers: make([]registry.Descriptor, 0, len(entry.Layers)),
+	}
+
+	for i, layerPath := range entry.Layers {
+		layer, err := l.importDockerLayer(layerPath, diffIDs[i])
+		if err != nil {
+			return nil, err
+		}
+		manifest.Layers = append(manifest.Layers, layer)
+	}
+
+	// Only keep the config once every layer checked out
+	if err := l.store.SaveBlob(configDigest, config); err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(manifest, "", "  ")
+}
+
+// importDockerLayer stores a docker save layer gzip-compressed and checks it against its diff ID
+// Layers are usually plain tars; gzip and zstd layers are decompressed first.
+func (l *archiveLoader) importDockerLayer(layerPath, diffID string) (registry.Descriptor, error) {
+	file, err := os.Open(archiveFilePath(l.dir, layerPath))
+	if err != nil {
+		return registry.Descriptor{}, fmt.Errorf("failed to open layer %s: %w", layerPath, err)
+	}
+	defer file.Close()
+
+	reader, err := decompressStream(bufio.NewReader(file))
+	if err != nil {
+		return registry.Descriptor{}, fmt.Errorf("failed to read layer %s: %w", layerPath, err)
+	}
+	defer reader.Close()
+
+	digest, size, _, err := l.store.importGzipLayer(reader, diffID)
+	var mismatch *registry.ErrDigestMismatch
+	if errors.As(err, &mismatch) {
+		return registry.Descriptor{}, fmt.Errorf("layer %s: %w", path.Clean(layerPath), err)
+	}
+	if err != nil {
+		return registry.Descriptor{}, fmt.Errorf("failed to import layer %s: %w", layerPath, err)
+	}
+
+	return registry.Descriptor{
+		MediaType: registry.MediaTypeDockerLayer,
+		Size:      size,
+		Digest:    digest,
+	}, nil
+}
+
+// decompressStream returns a reader for the uncompressed content of a gzip, zstd or plain stream
+func decompressStream(buffered *bufio.Reader) (io.ReadCloser, error) {
+	magic, _ := buffered.Peek(4)
+	switch {
+	case len(magic) >= 2 && magic[0] == 0x1f && magic[1] == 0x8b:
+		return gzip.NewReader(buffered)
+	case len(magic) == 4 && magic[0] == 0x28 && magic[1] == 0xb5 && magic[2] == 0x2f && magic[3] == 0xfd:
+		decoder, err := zstd.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	}
+	return io.NopCloser(buffered), nil
+}
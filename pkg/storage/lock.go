@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// errLocked is returned when a lock that isn't waited for is held by another process
+var errLocked = errors.New("locked by another process")
+
+// FileLock is an advisory lock on a file, held until Unlock
+type FileLock struct {
+	file *os.File
+}
+
+// acquireLock opens or creates path and locks it
+// A shared lock can be held by several processes at once, an exclusive one by
+// only one. With wait unset it fails with errLocked instead of waiting.
+func acquireLock(path string, exclusive, wait bool) (*FileLock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	if err := lockFile(file, exclusive, wait); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &FileLock{file: file}, nil
+}
+
+// Unlock releases the lock
+func (l *FileLock) Unlock() {
+	l.file.Close()
+}
+
+// isAt reports whether the locked file is still the one at path
+func (l *FileLock) isAt(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	locked, err := l.file.Stat()
+	if err != nil {
+		return false
+	}
+	return os.SameFile(info, locked)
+}
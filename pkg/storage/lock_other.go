@@ -0,0 +1,11 @@
+//go:build !unix
+
+package storage
+
+import "os"
+
+// lockFile does nothing where flock isn't available; GCOptions.MinAge is the only
+// protection for data being written there
+func lockFile(file *os.File, exclusive, wait bool) error {
+	return nil
+}
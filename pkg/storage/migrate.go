@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// migrate converts images stored in the old per-image layout into the shared blob store
+//
+// Old layout:
+//
+//	images/<name>/manifest.json
+//	images/<name>/config.json
+//	images/<name>/layers/sha256_<hex>.tar.gz
+//
+// New layout:
+//
+//	images/<name>/manifest.json
+//	blobs/sha256/<hex>
+//
+// Directories using the old encoding (both / and : replaced with _) are also
+// renamed to the _SLASH_/_COLON_ encoding.
+func (l *Layout) migrate() error {
+	imagesDir := filepath.Join(l.rootDir, "images")
+
+	entries, err := os.ReadDir(imagesDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read images directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		imageDir := filepath.Join(imagesDir, entry.Name())
+		if err := l.migrateImageDir(imageDir); err != nil {
+			return fmt.Errorf("failed to migrate %s: %w", entry.Name(), err)
+		}
+
+		if err := l.migrateOldEncoding(imagesDir, entry.Name()); err != nil {
+			return fmt.Errorf("failed to migrate %s: %w", entry.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// migrateImageDir moves the config and layers of a single image directory into the blob store
+func (l *Layout) migrateImageDir(imageDir string) error {
+	// Move config.json, keyed by the config digest from the manifest
+	configPath := filepath.Join(imageDir, "config.json")
+	if _, err := os.Stat(configPath); err == nil {
+		digest := readConfigDigest(filepath.Join(imageDir, "manifest.json"))
+		if _, _, err := splitDigest(digest); err != nil {
+			// Manifest missing or unreadable, fall back to hashing the file
+			if digest, err = fileDigest(configPath); err != nil {
+				return err
+			}
+		}
+
+		if err := l.importBlob(digest, configPath); err != nil {
+			return err
+		}
+	}
+
+	// Move layers/sha256_<hex>.tar.gz
+	layersDir := filepath.Join(imageDir, "layers")
+	files, err := os.ReadDir(layersDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read layers directory: %w", err)
+	}
+
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		// Filenames were the digest with : replaced by _
+		name := strings.TrimSuffix(file.Name(), ".tar.gz")
+		digest := strings.Replace(name, "_", ":", 1)
+		if _, _, err := splitDigest(digest); err != nil {
+			continue
+		}
+
+		if err := l.importBlob(digest, filepath.Join(layersDir, file.Name())); err != nil {
+			return err
+		}
+	}
+
+	return os.RemoveAll(layersDir)
+}
+
+// migrateOldEncoding renames a directory using the old encoding to the current encoding
+func (l *Layout) migrateOldEncoding(imagesDir, dirName string) error {
+	if strings.Contains(dirName, "_SLASH_") || strings.Contains(dirName, "_COLON_") {
+		return nil
+	}
+
+	imageName := decodeOldEncoding(dirName)
+	if !strings.Contains(imageName, ":") {
+		return nil
+	}
+
+	newPath := l.GetImageDir(imageName)
+	if _, err := os.Stat(newPath); err == nil {
+		// Already exists in the new encoding, keep both rather than guess
+		return nil
+	}
+
+	return os.Rename(filepath.Join(imagesDir, dirName), newPath)
+}
+
+// readConfigDigest reads the config digest from a manifest file
+func readConfigDigest(manifestPath string) string {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return ""
+	}
+
+	var manifest struct {
+		Config struct {
+			Digest string `json:"digest"`
+		} `json:"config"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return ""
+	}
+
+	return manifest.Config.Digest
+}
+
+// fileDigest computes the sha256 digest of a file
+func fileDigest(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"github.com/ioworker0/timage/pkg/registry"
+)
+
+// OCI image layout file names and annotations
+const (
+	OCILayoutFile    = "oci-layout"
+	OCIIndexFile     = "index.json"
+	OCILayoutVersion = "1.0.0"
+
+	AnnotationRefName = "org.opencontainers.image.ref.name"
+	// containerd reads the full image name from this annotation on import
+	AnnotationContainerdImageName = "io.containerd.image.name"
+)
+
+// ociLayout is the content of the oci-layout file
+type ociLayout struct {
+	ImageLayoutVersion string `json:"imageLayoutVersion"`
+}
+
+// ExportOCILayout writes stored images as an OCI image layout
+// Manifests are exported byte for byte, so images keep their digests. Every image is
+// listed in index.json with its name in the org.opencontainers.image.ref.name annotation.
+func (s *Store) ExportOCILayout(out ArchiveWriter, images []string) error {
+	layout, err := json.Marshal(ociLayout{ImageLayoutVersion: OCILayoutVersion})
+	if err != nil {
+		return err
+	}
+	if err := out.WriteFile(OCILayoutFile, layout); err != nil {
+		return err
+	}
+
+	index := registry.OCIIndex{
+		SchemaVersion: 2,
+		MediaType:     registry.MediaTypeOCIIndex,
+		Manifests:     []registry.Descriptor{},
+	}
+
+	written := make(map[string]bool)
+	for _, image := range images {
+		data, err := s.LoadManifestRaw(image)
+		if err != nil {
+			return err
+		}
+
+		digest := registry.ComputeDigest(data)
+		if err := s.exportManifest(out, digest, data, written); err != nil {
+			return fmt.Errorf("failed to export %s: %w", image, err)
+		}
+
+		index.Manifests = append(index.Manifests, registry.Descriptor{
+			MediaType: registry.DetectMediaType(data, ""),
+			Digest:    digest,
+			Size:      int64(len(data)),
+			Annotations: map[string]string{
+				AnnotationRefName:             image,
+				AnnotationContainerdImageName: image,
+			},
+		})
+	}
+
+	indexData, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return out.WriteFile(OCIIndexFile, indexData)
+}
+
+// exportManifest writes a manifest and everything it refers to into the blobs directory,
+// following the child manifests of an index
+func (s *Store) exportManifest(out ArchiveWriter, digest string, data []byte, written map[string]bool) error {
+	if !written[digest] {
+		name, err := blobArchivePath(digest)
+		if err != nil {
+			return err
+		}
+		if err := out.WriteFile(name, data); err != nil {
+			return err
+		}
+		written[digest] = true
+	}
+
+	if registry.IsManifestList(registry.DetectMediaType(data, "")) {
+		index, err := registry.ParseOCIIndex(data)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range index.Manifests {
+			child, err := s.LoadChildManifestRaw(entry.Digest)
+			if err != nil {
+				return err
+			}
+			if err := s.exportManifest(out, entry.Digest, child, written); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	digests, err := manifestReferences(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	for _, blob := range digests {
+		if written[blob] {
+			continue
+		}
+
+		blobPath, err := s.GetBlobPath(blob)
+		if err != nil {
+			return err
+		}
+		if !s.HasBlob(blob) {
+			return fmt.Errorf("blob %s is missing from the local store", blob)
+		}
+		name, err := blobArchivePath(blob)
+		if err != nil {
+			return err
+		}
+		if err := out.CopyFile(name, blobPath); err != nil {
+			return err
+		}
+		written[blob] = true
+	}
+
+	return nil
+}
+
+// blobArchivePath returns the path of a blob inside an image layout, blobs/<alg>/<hex>
+func blobArchivePath(digest string) (string, error) {
+	algorithm, encoded, err := splitDigest(digest)
+	if err != nil {
+		return "", err
+	}
+	return path.Join("blobs", algorithm, encoded), nil
+}
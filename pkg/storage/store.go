@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 
 	"github.com/ioworker0/timage/pkg/registry"
 )
@@ -41,7 +42,7 @@ func (s *Store) SaveManifest(imageName string, manifest *registry.Manifest) erro
 
 	// Write to file
 	manifestPath := s.layout.GetManifestPath(imageName)
-	if err := os.WriteFile(manifestPath, data, 0644); err != nil {
+	if err := writeFileAtomic(manifestPath, data); err != nil {
 		return fmt.Errorf("failed to write manifest: %w", err)
 	}
 
@@ -57,7 +58,7 @@ func (s *Store) SaveManifestRaw(imageName string, data []byte) error {
 
 	// Write to file
 	manifestPath := s.layout.GetManifestPath(imageName)
-	if err := os.WriteFile(manifestPath, data, 0644); err != nil {
+	if err := writeFileAtomic(manifestPath, data); err != nil {
 		return fmt.Errorf("failed to write manifest: %w", err)
 	}
 
@@ -93,27 +94,39 @@ func (s *Store) LoadManifestRaw(imageName string) ([]byte, error) {
 	return data, nil
 }
 
-// SaveConfig saves the config blob to disk
-func (s *Store) SaveConfig(imageName string, data []byte) error {
-	// Create image directory if it doesn't exist
-	if err := s.layout.CreateImageDir(imageName); err != nil {
-		return err
+// LoadConfig loads the config blob referenced by an image's manifest
+// A manifest list or index has no config of its own; use LoadPlatformConfig for those.
+func (s *Store) LoadConfig(imageName string) ([]byte, error) {
+	isIndex, err := s.IsIndex(imageName)
+	if err != nil {
+		return nil, err
+	}
+	if isIndex {
+		return nil, fmt.Errorf("image '%s' is a manifest list; specify a platform", imageName)
 	}
 
-	configPath := s.layout.GetConfigPath(imageName)
+	manifest, err := s.LoadManifest(imageName)
+	if err != nil {
+		return nil, err
+	}
 
-	if err := os.WriteFile(configPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write config: %w", err)
+	data, err := s.LoadBlob(manifest.Config.Digest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
 	}
 
-	return nil
+	return data, nil
 }
 
-// LoadConfig loads the config blob from disk
-func (s *Store) LoadConfig(imageName string) ([]byte, error) {
-	configPath := s.layout.GetConfigPath(imageName)
+// LoadPlatformConfig loads the config blob of an image for a platform
+// For a single-manifest image the platform is ignored.
+func (s *Store) LoadPlatformConfig(imageName string, platform registry.Platform) ([]byte, error) {
+	manifest, err := s.LoadPlatformManifest(imageName, platform)
+	if err != nil {
+		return nil, err
+	}
 
-	data, err := os.ReadFile(configPath)
+	data, err := s.LoadBlob(manifest.Config.Digest)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config: %w", err)
 	}
@@ -121,33 +134,200 @@ func (s *Store) LoadConfig(imageName string) ([]byte, error) {
 	return data, nil
 }
 
-// SaveLayer saves a layer blob to disk
-func (s *Store) SaveLayer(imageName, digest string, srcPath string) error {
-	// Create layers directory
-	layersDir := s.layout.GetLayersDir(imageName)
-	if err := os.MkdirAll(layersDir, 0755); err != nil {
+// LoadImageConfig loads and parses the config of an image for a platform
+func (s *Store) LoadImageConfig(imageName string, platform registry.Platform) (*registry.ImageConfig, error) {
+	data, err := s.LoadPlatformConfig(imageName, platform)
+	if err != nil {
+		return nil, err
+	}
+
+	return registry.ParseImageConfig(data)
+}
+
+// VerifyDiffIDs checks the diff IDs of an image config against the stored layers of its manifest
+// Every layer is decompressed to compute its diff ID. Layers that aren't filesystem
+// layers, such as attestations, and layers that aren't stored, such as foreign
+// layers, are skipped.
+func (s *Store) VerifyDiffIDs(manifest *registry.OCIManifest, config *registry.ImageConfig) error {
+	if err := config.ValidateDiffIDs(manifest.Layers); err != nil {
 		return err
 	}
 
-	// Destination path
-	destPath := s.layout.GetLayerPath(imageName, digest)
+	for i, layer := range manifest.Layers {
+		info, err := registry.GetLayerInfo(layer.MediaType)
+		if err != nil || !s.HasBlob(layer.Digest) {
+			continue
+		}
+
+		blobPath, err := s.layout.GetBlobPath(layer.Digest)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(blobPath)
+		if err != nil {
+			return fmt.Errorf("failed to open layer %s: %w", layer.Digest, err)
+		}
+		err = VerifyLayerDiffID(file, info.Compression, config.RootFS.DiffIDs[i])
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("layer %s: %w", layer.Digest, err)
+		}
+	}
+
+	return nil
+}
 
-	// Copy file
-	if err := copyFile(srcPath, destPath); err != nil {
-		return fmt.Errorf("failed to copy layer: %w", err)
+// HasBlob checks if a blob exists in the shared blob store
+func (s *Store) HasBlob(digest string) bool {
+	blobPath, err := s.layout.GetBlobPath(digest)
+	if err != nil {
+		return false
+	}
+
+	_, err = os.Stat(blobPath)
+	return err == nil
+}
+
+// GetBlobPath returns the path to a blob in the shared blob store
+func (s *Store) GetBlobPath(digest string) (string, error) {
+	return s.layout.GetBlobPath(digest)
+}
+
+// LockForWrite marks the store as being written to until the lock is released
+// Commands that store blobs before the manifest referring to them hold it, so
+// GarbageCollect doesn't remove the blobs in between. Any number of writers can
+// hold it at the same time.
+func (s *Store) LockForWrite() (*FileLock, error) {
+	return acquireLock(s.layout.GetLockPath(), false, true)
+}
+
+// GetPartialPath returns where a partial download of a blob is kept until it completes
+func (s *Store) GetPartialPath(digest string) (string, error) {
+	return s.layout.GetPartialPath(digest)
+}
+
+// LockPartial locks the partial download of a blob, waiting while another process
+// downloads the same blob
+// The partial file is created if it doesn't exist. The lock is on the file itself, so
+// while it is held the file may only be truncated, not replaced; moving it into the
+// blob store or removing it is left to the lock holder.
+func (s *Store) LockPartial(digest string) (*FileLock, error) {
+	partialPath, err := s.layout.GetPartialPath(digest)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(partialPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create downloads directory: %w", err)
+	}
+
+	for {
+		lock, err := acquireLock(partialPath, true, true)
+		if err != nil {
+			return nil, err
+		}
+
+		// The previous holder may have moved the file away; lock the one at the path now
+		if lock.isAt(partialPath) {
+			return lock, nil
+		}
+		lock.Unlock()
+	}
+}
+
+// ImportBlob moves a downloaded file into the shared blob store
+// The source file is consumed; if the blob is already stored it is simply removed.
+func (s *Store) ImportBlob(digest, srcPath string) error {
+	if err := s.layout.importBlob(digest, srcPath); err != nil {
+		return fmt.Errorf("failed to import blob %s: %w", digest, err)
 	}
 
 	return nil
 }
 
-// GetLayerPath returns the path to a layer
-func (s *Store) GetLayerPath(imageName, digest string) string {
-	return s.layout.GetLayerPath(imageName, digest)
+// SaveBlob writes blob data to the shared blob store
+func (s *Store) SaveBlob(digest string, data []byte) error {
+	blobPath, err := s.layout.GetBlobPath(digest)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(blobPath), 0755); err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	if err := writeFileAtomic(blobPath, data); err != nil {
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+
+	return nil
+}
+
+// LoadBlob loads a blob from the shared blob store
+func (s *Store) LoadBlob(digest string) ([]byte, error) {
+	blobPath, err := s.layout.GetBlobPath(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(blobPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob: %w", err)
+	}
+
+	return data, nil
+}
+
+// OpenBlob opens a blob in the shared blob store for reading
+func (s *Store) OpenBlob(digest string) (io.ReadCloser, error) {
+	blobPath, err := s.layout.GetBlobPath(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(blobPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blob: %w", err)
+	}
+
+	return file, nil
+}
+
+// TagImage creates a new image name referring to the same manifest as an existing image
+// Only the manifest is copied; config and layers are shared through the blob store.
+func (s *Store) TagImage(source, target string) error {
+	data, err := s.LoadManifestRaw(source)
+	if err != nil {
+		return err
+	}
+
+	return s.SaveManifestRaw(target, data)
 }
 
-// GetConfigPath returns the path to the config file
-func (s *Store) GetConfigPath(imageName string) string {
-	return s.layout.GetConfigPath(imageName)
+// BlobReferences maps each blob digest to the names of the local images referencing it
+func (s *Store) BlobReferences() (map[string][]string, error) {
+	images, err := s.ListImages()
+	if err != nil {
+		return nil, err
+	}
+
+	references := make(map[string][]string)
+	for _, image := range images {
+		data, err := s.LoadManifestRaw(image)
+		if err != nil {
+			continue
+		}
+
+		digests, err := s.imageReferences(data)
+		if err != nil {
+			continue
+		}
+
+		for _, digest := range digests {
+			references[digest] = append(references[digest], image)
+		}
+	}
+
+	return references, nil
 }
 
 // ListImages returns a list of all stored images
@@ -161,10 +341,44 @@ func (s *Store) ImageExists(imageName string) bool {
 }
 
 // RemoveImage removes an image from storage
+// Blobs are shared between images and are left in place.
 func (s *Store) RemoveImage(imageName string) error {
 	return s.layout.RemoveImage(imageName)
 }
 
+// writeFileAtomic writes data to a temp file next to path and renames it in place,
+// so a partially written file is never visible under its final name
+func writeFileAtomic(path string, data []byte) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return nil
+}
+
 // copyFile copies a file from src to dst
 func copyFile(src, dst string) error {
 	sourceFile, err := os.Open(src)